@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"github.com/morgine/pkg/app"
+	"github.com/morgine/pkg/config"
+	"github.com/morgine/pkg/crypt/kdf"
+	"github.com/morgine/pkg/crypt/keyring"
+	"github.com/morgine/pkg/crypt/password"
+	"github.com/morgine/pkg/session"
+	"gorm.io/gorm"
+)
+
+func init() {
+	config.RegisterSchema("admin", "管理员配置", Config{})
+}
+
+// Config 管理员配置
+type Config struct {
+	AuthExpires int64           `toml:"auth_expires" default:"86400" desc:"会话过期时间(单位: 秒)" validate:"min=1"`
+	AesCryptKey string          `toml:"aes_crypt_key" desc:"token 加密密钥, 16, 24 或 32 位字符串, 配置密钥环后仅用于解密旧 token" validate:"aes_key" secret:"true"`
+	KeyRing     keyring.Config  `toml:"keyring" desc:"token 密钥环"`
+	Password    password.Config `toml:"password" desc:"密码哈希"`
+	KDF         kdf.Config      `toml:"kdf" desc:"token 密钥派生, 设置 secret 后由其派生 aes_crypt_key, 可使用任意长度的口令"`
+}
+
+// Component 管理员组件, db 为 gorm 组件(*gorm.DB)名称, storage 为 token 存储器组件(session.Storage)名称,
+// 实例类型为 *Handler
+func Component(name, namespace, db, storage string) app.Component {
+	return app.Component{
+		Name:      name,
+		Namespace: namespace,
+		Config:    Config{},
+		Requires:  []string{db, storage},
+		New: func(c *app.Context) (interface{}, error) {
+			cfg := c.Config().(*Config)
+			opts := &Options{
+				DB:          c.Get(db).(*gorm.DB),
+				Session:     c.Get(storage).(session.Storage),
+				AuthExpires: cfg.AuthExpires,
+				AesCryptKey: []byte(cfg.AesCryptKey),
+			}
+			if cfg.KDF.Secret != "" {
+				master, err := cfg.KDF.New()
+				if err != nil {
+					return nil, err
+				}
+				opts.AesCryptKey, err = master.Key(kdf.PurposeToken, 32)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if len(cfg.KeyRing.Keys) > 0 {
+				keys, err := cfg.KeyRing.New()
+				if err != nil {
+					return nil, err
+				}
+				opts.Keys = keys
+			}
+			passwords, err := password.New(cfg.Password)
+			if err != nil {
+				return nil, err
+			}
+			opts.Passwords = passwords
+			return NewHandler(opts)
+		},
+	}
+}
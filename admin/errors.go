@@ -5,4 +5,7 @@ import "errors"
 var (
 	ErrUsernameAlreadyExist         = errors.New("用户名已存在")
 	ErrMismatchedUsernameOrPassword = errors.New("用户名或密码错误")
+	ErrInvalidToken                 = errors.New("token 无效")
+	ErrPasswordTooShort             = errors.New("密码长度不足")
+	ErrPasswordBreached             = errors.New("密码已泄露, 请更换密码")
 )
@@ -2,11 +2,18 @@ package admin
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"github.com/morgine/pkg/config"
 	"github.com/morgine/pkg/crypt/aes"
+	"github.com/morgine/pkg/crypt/jose"
+	"github.com/morgine/pkg/crypt/keyring"
+	"github.com/morgine/pkg/crypt/password"
 	"github.com/morgine/pkg/session"
 	"gorm.io/gorm"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,19 +25,50 @@ type Handler struct {
 }
 
 type Options struct {
-	DB          *gorm.DB        // 数据库 ORM
-	Session     session.Storage // token 存储器
-	AuthExpires int64           // 会话过期时间
-	AesCryptKey []byte          // 16 位字符串
+	DB          *gorm.DB         `validate:"required"` // 数据库 ORM
+	Session     session.Storage  `validate:"required"` // token 存储器
+	AuthExpires int64            `validate:"min=1"`    // 会话过期时间
+	AesCryptKey []byte           `validate:"aes_key"`  // 16, 24 或 32 位字符串, 设置 Keys 后仅用于解密旧 token
+	Keys        *keyring.KeyRing // token 密钥环, 轮换密钥后旧 token 仍然有效
+	Passwords   *password.Hasher // 密码哈希器, 为空时使用 password.Default, 登陆时自动升级旧的哈希值
+	// JWTKey 设置后签发 JWS token, sub 为管理员 ID, 过期时间为签发时间加 AuthExpires, 不随刷新延长,
+	// 其他服务可通过 JWKS 导出的公钥验证 token, 旧的 token 仍由 Keys 或 AesCryptKey 解密
+	JWTKey *jose.Key
+}
+
+func init() {
+	config.RegisterValidator("aes_key", func(value reflect.Value, _ string) error {
+		isBytes := value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8
+		if value.Kind() != reflect.String && !isBytes {
+			return fmt.Errorf("AES 密钥必须为字符串或字节切片, 当前类型为 %s", value.Type())
+		}
+		switch value.Len() {
+		case 0, 16, 24, 32:
+			return nil
+		default:
+			return fmt.Errorf("AES 密钥长度必须为 16, 24 或 32 位, 当前长度为 %d, 口令请使用 kdf 派生密钥", value.Len())
+		}
+	})
 }
 
 func NewHandler(opts *Options) (*Handler, error) {
-	err := opts.DB.AutoMigrate(&Admin{})
+	err := config.ValidateNamespace("admin", opts)
 	if err != nil {
 		return nil, err
 	}
+	if opts.JWTKey == nil && opts.Keys == nil && len(opts.AesCryptKey) == 0 {
+		return nil, errors.New("admin: JWTKey, Keys 或 AesCryptKey 必须设置其中之一")
+	}
+	err = opts.DB.AutoMigrate(&Admin{})
+	if err != nil {
+		return nil, err
+	}
+	passwords := opts.Passwords
+	if passwords == nil {
+		passwords = password.Default
+	}
 	return &Handler{
-		m:    &model{opts.DB},
+		m:    &model{db: opts.DB, passwords: passwords},
 		opts: opts,
 	}, nil
 }
@@ -101,18 +139,58 @@ func (h *Handler) Logout(adminID int, token string) error {
 	return h.opts.Session.RemoveToken(strconv.Itoa(adminID), token)
 }
 
+// JWKS 返回 JWTKey 的公钥集, HS256 密钥不会导出
+func (h *Handler) JWKS() jose.JWKS {
+	if h.opts.JWTKey == nil {
+		return jose.JWKS{Keys: []jose.JWK{}}
+	}
+	return jose.NewKeySet(h.opts.JWTKey).JWKS()
+}
+
 // token 加密
 func (h *Handler) encryptToken(adminID string) (token string, err error) {
-	return aes.AesCBCEncrypt([]byte(fmt.Sprintf("%s:%10d", adminID, Now().UnixNano())), h.opts.AesCryptKey)
+	if h.opts.JWTKey != nil {
+		// 签发及过期时间使用 admin.Now, 与其他 token 共用同一时钟
+		claims := jose.NewClaims(adminID, 0)
+		now := Now()
+		claims.IssuedAt = now.Unix()
+		claims.ExpiresAt = now.Add(time.Duration(h.opts.AuthExpires) * time.Second).Unix()
+		return jose.Sign(h.opts.JWTKey, claims)
+	}
+	data := []byte(fmt.Sprintf("%s:%10d", adminID, Now().UnixNano()))
+	if h.opts.Keys != nil {
+		return h.opts.Keys.SealString(data, nil)
+	}
+	return aes.AesCBCEncrypt(data, h.opts.AesCryptKey)
 }
 
-// token 解密
+// token 解密, 任何解密错误都返回 ErrInvalidToken, 避免向调用方泄露填充等错误细节
 func (h *Handler) decryptToken(token string) (adminID string, err error) {
-	data, err := aes.AesCBCDecrypt(token, h.opts.AesCryptKey)
+	if h.opts.JWTKey != nil && strings.Count(token, ".") == 2 {
+		claims := &jose.Claims{}
+		err = jose.NewKeySet(h.opts.JWTKey).Verify(token, claims, jose.Expectation{Now: Now})
+		if err != nil {
+			return "", ErrInvalidToken
+		}
+		return claims.Subject, nil
+	}
+	var data []byte
+	if h.opts.Keys != nil {
+		data, err = h.opts.Keys.OpenString(token, nil)
+		// 兼容启用密钥环之前的 token
+		if err != nil && len(h.opts.AesCryptKey) > 0 {
+			data, err = aes.AesCBCDecrypt(token, h.opts.AesCryptKey)
+		}
+	} else {
+		data, err = aes.AesCBCDecrypt(token, h.opts.AesCryptKey)
+	}
 	if err != nil {
-		return "", err
+		return "", ErrInvalidToken
 	} else {
 		sepIdx := bytes.Index(data, []byte(":"))
+		if sepIdx < 0 {
+			return "", ErrInvalidToken
+		}
 		return string(data[:sepIdx]), nil
 	}
 }
@@ -0,0 +1,206 @@
+package admin
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"github.com/morgine/pkg/config"
+	"github.com/morgine/pkg/crypt/aead"
+	"github.com/morgine/pkg/crypt/jose"
+	"github.com/morgine/pkg/crypt/keyring"
+	"github.com/morgine/pkg/crypt/password"
+	"github.com/morgine/pkg/session"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestHandler(t *testing.T) (*Handler, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := password.DefaultConfig()
+	cfg.Argon2Memory, cfg.Argon2Time, cfg.Argon2Threads = 64, 1, 1
+	passwords, err := password.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := NewHandler(&Options{
+		DB:          db,
+		Session:     session.NewMemoryStorage(0),
+		AuthExpires: 60,
+		AesCryptKey: []byte("change this pass"),
+		Passwords:   passwords,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, db
+}
+
+func TestHandler(t *testing.T) {
+	h, _ := newTestHandler(t)
+	if err := h.RegisterAdmin("admin", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Login("admin", "wrong horse"); err != ErrMismatchedUsernameOrPassword {
+		t.Errorf("need: %v, got: %v\n", ErrMismatchedUsernameOrPassword, err)
+	}
+	token, err := h.Login("admin", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminID, err := h.CheckAndRefreshToken(token)
+	if err != nil || adminID == 0 {
+		t.Fatalf("need admin ID, got: %d %v\n", adminID, err)
+	}
+	if err := h.Logout(adminID, token); err != nil {
+		t.Fatal(err)
+	}
+	if adminID, err := h.CheckAndRefreshToken(token); err != nil || adminID != 0 {
+		t.Errorf("need logged out, got: %d %v\n", adminID, err)
+	}
+	// padding and decoding errors are not distinguishable
+	raw, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-aes.BlockSize-1] ^= 1
+	for _, tampered := range []string{base64.URLEncoding.EncodeToString(raw), "!", token[:len(token)-4]} {
+		checkToken(t, h, tampered, ErrInvalidToken)
+	}
+}
+
+// withOptions returns a handler sharing the database and session of h with changed options
+func withOptions(t *testing.T, h *Handler, change func(opts *Options)) *Handler {
+	opts := *h.opts
+	change(&opts)
+	nh, err := NewHandler(&opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nh
+}
+
+func checkToken(t *testing.T, h *Handler, token string, needErr error) {
+	t.Helper()
+	adminID, err := h.CheckAndRefreshToken(token)
+	if err != needErr {
+		t.Fatalf("need: %v, got: %v\n", needErr, err)
+	}
+	if err == nil && adminID == 0 {
+		t.Errorf("need admin ID, got: 0\n")
+	}
+}
+
+func TestKeyRingToken(t *testing.T) {
+	legacy, _ := newTestHandler(t)
+	if err := legacy.RegisterAdmin("admin", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	legacyToken, err := legacy.Login("admin", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := keyring.New()
+	if err := keys.Add("k1", aead.AESGCM, bytes.Repeat([]byte{1}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	h := withOptions(t, legacy, func(opts *Options) {
+		opts.Keys = keys
+	})
+	token, err := h.Login("admin", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := keyring.KeyID(data); err != nil || id != "k1" {
+		t.Errorf("need: k1, got: %s %v\n", id, err)
+	}
+	checkToken(t, h, token, nil)
+	// tokens issued before Keys are decrypted by AesCryptKey
+	checkToken(t, h, legacyToken, nil)
+
+	// tokens of the previous key are valid after rotation
+	if err := keys.Rotate("k2", aead.XChaCha20Poly1305, bytes.Repeat([]byte{2}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := h.Login("admin", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkToken(t, h, token, nil)
+	checkToken(t, h, rotated, nil)
+}
+
+func TestJWTToken(t *testing.T) {
+	legacy, _ := newTestHandler(t)
+	if err := legacy.RegisterAdmin("admin", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	legacyToken, err := legacy.Login("admin", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := withOptions(t, legacy, func(opts *Options) {
+		opts.JWTKey = jose.NewEdDSA("admin", edKey)
+	})
+	token, err := h.Login("admin", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(token, ".") != 2 {
+		t.Fatalf("need JWS token, got: %s\n", token)
+	}
+	checkToken(t, h, token, nil)
+	if keys := h.JWKS().Keys; len(keys) != 1 {
+		t.Errorf("need: 1 key, got: %v\n", keys)
+	}
+	// tokens issued before JWTKey are decrypted by AesCryptKey
+	checkToken(t, h, legacyToken, nil)
+	checkToken(t, h, token+"x", ErrInvalidToken)
+
+	// the expiry is checked by admin.Now
+	now := time.Now().Add(time.Duration(h.opts.AuthExpires+1) * time.Second)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+	checkToken(t, h, token, ErrInvalidToken)
+}
+
+func TestAesKeyValidator(t *testing.T) {
+	tests := []struct {
+		schema interface{}
+		valid  bool
+	}{
+		{&struct {
+			Key string `validate:"aes_key"`
+		}{Key: "change this pass"}, true},
+		{&struct {
+			Key []byte `validate:"aes_key"`
+		}{Key: []byte("short")}, false},
+		{&struct {
+			Key int `validate:"aes_key"`
+		}{Key: 16}, false},
+		{&struct {
+			Key []int `validate:"aes_key"`
+		}{Key: make([]int, 16)}, false},
+	}
+	for i, test := range tests {
+		err := config.Validate(test.schema)
+		if (err == nil) != test.valid {
+			t.Errorf("%d: need valid: %v, got: %v\n", i, test.valid, err)
+		}
+	}
+}
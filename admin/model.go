@@ -1,7 +1,7 @@
 package admin
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"github.com/morgine/pkg/crypt/password"
 	"gorm.io/gorm"
 )
 
@@ -12,7 +12,8 @@ type Admin struct {
 }
 
 type model struct {
-	db *gorm.DB
+	db        *gorm.DB
+	passwords *password.Hasher
 }
 
 // RegisterAdmin 注册账号，如果账号已存在则返回 ErrUsernameAlreadyExist 错误
@@ -24,11 +25,11 @@ func (m *model) RegisterAdmin(username, password string) (err error) {
 	if admin != nil {
 		return ErrUsernameAlreadyExist
 	} else {
-		password, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+		hash, err := m.hashPassword(password)
 		if err != nil {
 			return err
 		}
-		return m.db.Create(&Admin{Username: username, Password: string(password)}).Error
+		return m.db.Create(&Admin{Username: username, Password: hash}).Error
 	}
 }
 
@@ -40,16 +41,21 @@ func (m *model) LoginAdmin(username, password string) (*Admin, error) {
 	if admin == nil {
 		return nil, ErrMismatchedUsernameOrPassword
 	} else {
-		err = bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password))
+		ok, err := m.passwords.Verify(password, admin.Password)
 		if err != nil {
-			if err == bcrypt.ErrMismatchedHashAndPassword {
-				return nil, ErrMismatchedUsernameOrPassword
-			} else {
-				return nil, err
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrMismatchedUsernameOrPassword
+		}
+		// 使用当前算法及参数升级旧的哈希值, 升级失败不影响登陆
+		if m.passwords.NeedsRehash(admin.Password) {
+			hash, err := m.passwords.Hash(password)
+			if err == nil && m.db.Model(admin).Update("password", hash).Error == nil {
+				admin.Password = hash
 			}
-		} else {
-			return admin, nil
 		}
+		return admin, nil
 	}
 }
 
@@ -80,9 +86,20 @@ func (m *model) GetAdminByID(id int) (*Admin, error) {
 }
 
 func (m *model) ResetPassword(authAdminID int, newPassword string) error {
-	password, err := bcrypt.GenerateFromPassword([]byte(newPassword), 10)
+	hash, err := m.hashPassword(newPassword)
 	if err != nil {
 		return err
 	}
-	return m.db.Where("id=?", authAdminID).Updates(&Admin{Password: string(password)}).Error
+	return m.db.Where("id=?", authAdminID).Updates(&Admin{Password: hash}).Error
+}
+
+// hashPassword 检查密码策略并计算哈希值
+func (m *model) hashPassword(pwd string) (string, error) {
+	switch m.passwords.Check(pwd) {
+	case password.ErrTooShort:
+		return "", ErrPasswordTooShort
+	case password.ErrBreached:
+		return "", ErrPasswordBreached
+	}
+	return m.passwords.Hash(pwd)
 }
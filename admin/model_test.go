@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	h, _ := newTestHandler(t)
+	if err := h.RegisterAdmin("admin", "short"); err != ErrPasswordTooShort {
+		t.Errorf("need: %v, got: %v\n", ErrPasswordTooShort, err)
+	}
+	if err := h.RegisterAdmin("admin", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	admin, err := h.m.GetAdminByUsername("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.ResetPassword(admin.ID, "short"); err != ErrPasswordTooShort {
+		t.Errorf("need: %v, got: %v\n", ErrPasswordTooShort, err)
+	}
+}
+
+func TestLoginRehash(t *testing.T) {
+	h, db := newTestHandler(t)
+	old, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Admin{Username: "admin", Password: string(old)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Login("admin", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	admin, err := h.m.GetAdminByUsername("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.m.passwords.NeedsRehash(admin.Password) {
+		t.Errorf("need upgraded hash, got: %s\n", admin.Password)
+	}
+	if _, err := h.Login("admin", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+}
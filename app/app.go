@@ -0,0 +1,307 @@
+// Copyright 2020 morgine.com. All rights reserved.
+
+// Package app constructs the components of an application from configs, components are registered
+// with a config namespace, a config struct and a constructor, Bootstrap validates every namespace up
+// front, constructs the components in dependency order and closes them in reverse order, for example:
+//
+//	app.Register(mysql.Component("mysql", "mysql"))
+//	app.Register(orm.Component("gorm", "gorm", "mysql", orm.NewMysqlDialector))
+//	app.Register(redis.Component("redis", "redis"))
+//	app.Register(session.RedisComponent("session", "admin_", "redis"))
+//	app.Register(admin.Component("admin", "admin", "gorm", "session"))
+//
+//	a, err := app.BootstrapFile("app.toml")
+//	if err != nil {
+//		panic(err)
+//	}
+//	defer a.Close()
+//	handler := a.Get("admin").(*admin.Handler)
+package app
+
+import (
+	"fmt"
+	"github.com/morgine/pkg/config"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Component is constructed from the configs of Namespace
+type Component struct {
+	Name      string      // unique name, used by Requires and Get
+	Namespace string      // config namespace, the component has no configs if Namespace or Config is empty
+	Config    interface{} // config struct, such as mysql.Config{}, decoded and validated before any component is constructed
+	Requires  []string    // names of the components which must be constructed before this one
+	// New constructs the component, c.Config() returns the decoded config struct
+	New func(c *Context) (interface{}, error)
+	// Close releases the component, if nil the Close method is called if the component implements io.Closer
+	Close func(instance interface{}) error
+}
+
+// Registry keeps the registered components in registration order
+type Registry struct {
+	mu         sync.RWMutex
+	components []Component
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register registers c, a component with the same name is replaced
+func (r *Registry) Register(c Component) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, exist := range r.components {
+		if exist.Name == c.Name {
+			r.components[i] = c
+			return
+		}
+	}
+	r.components = append(r.components, c)
+}
+
+// Components returns the registered components in registration order
+func (r *Registry) Components() []Component {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Component(nil), r.components...)
+}
+
+// DefaultRegistry is used by Register, Bootstrap and BootstrapFile
+var DefaultRegistry = NewRegistry()
+
+// Register registers c to DefaultRegistry
+func Register(c Component) {
+	DefaultRegistry.Register(c)
+}
+
+// Bootstrap constructs the components of DefaultRegistry from cs
+func Bootstrap(cs config.Configs) (*App, error) {
+	return DefaultRegistry.Bootstrap(cs)
+}
+
+// BootstrapFile loads filename and constructs the components of DefaultRegistry
+func BootstrapFile(filename string) (*App, error) {
+	cs, err := config.UnmarshalFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return DefaultRegistry.Bootstrap(cs)
+}
+
+// BootstrapStrict constructs the components of DefaultRegistry by the strict decoder
+func BootstrapStrict(decoder *config.StrictDecoder) (*App, error) {
+	return DefaultRegistry.BootstrapStrict(decoder)
+}
+
+// Errors combines the errors of every failed component
+type Errors []error
+
+func (es Errors) Error() string {
+	msgs := make([]string, len(es))
+	for i, err := range es {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ComponentError is the error of a single component
+type ComponentError struct {
+	Name string
+	Op   string // "config", "new" or "close"
+	err  error
+}
+
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("app: %s component %s error: %s", e.Op, e.Name, e.err)
+}
+
+func (e *ComponentError) Unwrap() error {
+	return e.err
+}
+
+// Context is passed to the constructor of a component
+type Context struct {
+	Configs config.Configs
+	name    string
+	config  interface{}
+	app     *App
+}
+
+// Name returns the name of the component
+func (c *Context) Name() string {
+	return c.name
+}
+
+// Config returns the pointer of the decoded config struct, or nil if the component has no configs
+func (c *Context) Config() interface{} {
+	return c.config
+}
+
+// Get returns the constructed component of name, name should be listed in Requires
+func (c *Context) Get(name string) interface{} {
+	return c.app.Get(name)
+}
+
+// App holds the constructed components
+type App struct {
+	configs   config.Configs
+	mu        sync.Mutex
+	order     []Component
+	instances map[string]interface{}
+}
+
+// Bootstrap decodes and validates the configs of every component, the components are constructed
+// only if all configs are valid, otherwise all config errors are returned. If a constructor fails,
+// the constructed components are closed in reverse order.
+func (r *Registry) Bootstrap(cs config.Configs) (*App, error) {
+	return r.bootstrap(cs, cs.UnmarshalSub, nil)
+}
+
+// BootstrapStrict is the same as Bootstrap but decodes the namespaces by decoder, unknown keys are config
+// errors of the components, and the namespaces which are not decoded by any component are reported by
+// decoder.Check. Namespaces used outside the components should be decoded by decoder before bootstrap.
+func (r *Registry) BootstrapStrict(decoder *config.StrictDecoder) (*App, error) {
+	return r.bootstrap(decoder.Configs(), decoder.UnmarshalSub, decoder.Check)
+}
+
+// bootstrap decodes the namespaces by unmarshal, check is called after decoding if it is not nil
+func (r *Registry) bootstrap(cs config.Configs, unmarshal func(namespace string, schema interface{}) error,
+	check func() error) (*App, error) {
+	order, err := sortComponents(r.Components())
+	if err != nil {
+		return nil, err
+	}
+	configs := make(map[string]interface{}, len(order))
+	var errs Errors
+	for _, c := range order {
+		if c.Namespace == "" || c.Config == nil {
+			continue
+		}
+		rt := reflect.TypeOf(c.Config)
+		for rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		schema := reflect.New(rt).Interface()
+		err = unmarshal(c.Namespace, schema)
+		if err != nil {
+			errs = append(errs, &ComponentError{Name: c.Name, Op: "config", err: err})
+			continue
+		}
+		configs[c.Name] = schema
+	}
+	if check != nil {
+		if err := check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	a := &App{configs: cs, instances: make(map[string]interface{}, len(order))}
+	for _, c := range order {
+		instance, err := c.New(&Context{Configs: cs, name: c.Name, config: configs[c.Name], app: a})
+		if err != nil {
+			errs = append(errs, &ComponentError{Name: c.Name, Op: "new", err: err})
+			if err := a.Close(); err != nil {
+				errs = append(errs, err.(Errors)...)
+			}
+			return nil, errs
+		}
+		a.mu.Lock()
+		a.order = append(a.order, c)
+		a.instances[c.Name] = instance
+		a.mu.Unlock()
+	}
+	return a, nil
+}
+
+// Configs returns the configs which the components are constructed from
+func (a *App) Configs() config.Configs {
+	return a.configs
+}
+
+// Get returns the constructed component of name, or nil if not exist
+func (a *App) Get(name string) interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.instances[name]
+}
+
+// Close closes the components in reverse construction order, every component is closed even if some
+// of them fail, the failures are returned as Errors
+func (a *App) Close() error {
+	a.mu.Lock()
+	order := a.order
+	a.order = nil
+	a.mu.Unlock()
+	var errs Errors
+	for i := len(order) - 1; i >= 0; i-- {
+		c := order[i]
+		instance := a.Get(c.Name)
+		var err error
+		if c.Close != nil {
+			err = c.Close(instance)
+		} else if closer, ok := instance.(io.Closer); ok {
+			err = closer.Close()
+		}
+		if err != nil {
+			errs = append(errs, &ComponentError{Name: c.Name, Op: "close", err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// sortComponents sorts the components in dependency order, components without dependencies between
+// them keep the registration order
+func sortComponents(components []Component) ([]Component, error) {
+	index := make(map[string]int, len(components))
+	for i, c := range components {
+		index[c.Name] = i
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(components))
+	order := make([]Component, 0, len(components))
+	var stack []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		c := components[i]
+		switch states[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("app: dependency cycle %s -> %s", strings.Join(stack, " -> "), c.Name)
+		}
+		states[i] = visiting
+		stack = append(stack, c.Name)
+		for _, name := range c.Requires {
+			dep, ok := index[name]
+			if !ok {
+				return fmt.Errorf("app: component %s requires unregistered component %s", c.Name, name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[i] = visited
+		order = append(order, c)
+		return nil
+	}
+	for i := range components {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,235 @@
+package app
+
+import (
+	"errors"
+	"github.com/morgine/pkg/config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	Addr string `toml:"addr" validate:"required"`
+	Size int    `toml:"size" default:"10" validate:"min=1"`
+}
+
+type testComponent struct {
+	name   string
+	closed *[]string
+	err    error
+}
+
+func (c *testComponent) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.err
+}
+
+func newTestRegistry(created, closed *[]string) *Registry {
+	r := NewRegistry()
+	add := func(name string, requires ...string) {
+		r.Register(Component{
+			Name:      name,
+			Namespace: name,
+			Config:    testConfig{},
+			Requires:  requires,
+			New: func(c *Context) (interface{}, error) {
+				for _, dep := range requires {
+					if c.Get(dep) == nil {
+						return nil, errors.New("missing " + dep)
+					}
+				}
+				if c.Config().(*testConfig).Addr == "fail" {
+					return nil, errors.New("connect failed")
+				}
+				*created = append(*created, c.Name())
+				return &testComponent{name: c.Name(), closed: closed}, nil
+			},
+		})
+	}
+	add("admin", "gorm", "session")
+	add("gorm", "mysql")
+	add("session", "redis")
+	add("mysql")
+	add("redis")
+	return r
+}
+
+func TestBootstrap(t *testing.T) {
+	cs, err := config.UnmarshalMemory([]byte(`
+[admin]
+addr = "admin"
+[gorm]
+addr = "gorm"
+[session]
+addr = "session"
+[mysql]
+addr = "mysql"
+[redis]
+addr = "redis"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created, closed []string
+	a, err := newTestRegistry(&created, &closed).Bootstrap(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	need := []string{"mysql", "gorm", "redis", "session", "admin"}
+	if !reflect.DeepEqual(need, created) {
+		t.Errorf("need: %v, got: %v\n", need, created)
+	}
+	if a.Get("admin").(*testComponent).name != "admin" {
+		t.Error("admin component not found")
+	}
+	err = a.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	need = []string{"admin", "session", "redis", "gorm", "mysql"}
+	if !reflect.DeepEqual(need, closed) {
+		t.Errorf("need: %v, got: %v\n", need, closed)
+	}
+}
+
+func TestBootstrapStrict(t *testing.T) {
+	cs, err := config.UnmarshalMemory([]byte(`
+[admin]
+addr = "admin"
+[gorm]
+addr = "gorm"
+[session]
+addr = "session"
+[mysql]
+addr = "mysql"
+[redis]
+addr = "redis"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created, closed []string
+	decoder := config.NewStrictDecoder(cs, nil)
+	a, err := newTestRegistry(&created, &closed).BootstrapStrict(decoder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if err = decoder.Check(); err != nil {
+		t.Errorf("need no unused namespace, got: %v\n", err)
+	}
+
+	cs["cache"] = map[string]interface{}{"addr": "cache"}
+	cs["redis"].(map[string]interface{})["timeout"] = 3
+	created = nil
+	_, err = newTestRegistry(&created, &closed).BootstrapStrict(config.NewStrictDecoder(cs, nil))
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 || len(created) != 0 {
+		t.Fatalf("need 2 errors and no component, got: %v, %v\n", err, created)
+	}
+	for _, need := range []string{"redis.timeout", "unused namespaces: cache"} {
+		if !strings.Contains(err.Error(), need) {
+			t.Errorf("need error of %s, got: %v\n", need, err)
+		}
+	}
+}
+
+func TestBootstrapConfigErrors(t *testing.T) {
+	cs, err := config.UnmarshalMemory([]byte(`
+[admin]
+addr = "admin"
+[gorm]
+size = 0
+[session]
+addr = "session"
+[mysql]
+addr = "mysql"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created, closed []string
+	_, err = newTestRegistry(&created, &closed).Bootstrap(cs)
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("need Errors, got: %v\n", err)
+	}
+	// every invalid namespace is reported and nothing is constructed
+	if len(errs) != 2 || len(created) != 0 {
+		t.Errorf("need 2 errors and no component, got: %v, %v\n", errs, created)
+	}
+	for _, name := range []string{"gorm", "redis"} {
+		if !strings.Contains(err.Error(), "component "+name) {
+			t.Errorf("need error of %s, got: %v\n", name, err)
+		}
+	}
+}
+
+func TestBootstrapNewError(t *testing.T) {
+	cs, err := config.UnmarshalMemory([]byte(`
+[admin]
+addr = "fail"
+[gorm]
+addr = "gorm"
+[session]
+addr = "session"
+[mysql]
+addr = "mysql"
+[redis]
+addr = "redis"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created, closed []string
+	_, err = newTestRegistry(&created, &closed).Bootstrap(cs)
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("need Errors, got: %v\n", err)
+	}
+	var ce *ComponentError
+	if !errors.As(errs[0], &ce) || ce.Name != "admin" || ce.Op != "new" {
+		t.Fatalf("need new admin error, got: %v\n", err)
+	}
+	need := []string{"session", "redis", "gorm", "mysql"}
+	if !reflect.DeepEqual(need, closed) {
+		t.Errorf("need: %v, got: %v\n", need, closed)
+	}
+}
+
+func TestBootstrapCycle(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Component{Name: "a", Requires: []string{"b"}})
+	r.Register(Component{Name: "b", Requires: []string{"a"}})
+	_, err := r.Bootstrap(config.Configs{})
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("need cycle error, got: %v\n", err)
+	}
+	r = NewRegistry()
+	r.Register(Component{Name: "a", Requires: []string{"c"}})
+	_, err = r.Bootstrap(config.Configs{})
+	if err == nil || !strings.Contains(err.Error(), "unregistered component c") {
+		t.Errorf("need unregistered error, got: %v\n", err)
+	}
+}
+
+func TestBootstrapWithoutNamespace(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Component{
+		Name:   "clock",
+		Config: testConfig{},
+		New: func(c *Context) (interface{}, error) {
+			if c.Config() != nil {
+				return nil, errors.New("need no config")
+			}
+			return "clock", nil
+		},
+	})
+	a, err := r.Bootstrap(config.Configs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Get("clock"); got != "clock" {
+		t.Errorf("need: clock, got: %v\n", got)
+	}
+}
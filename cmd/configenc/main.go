@@ -0,0 +1,42 @@
+// configenc 使用主密钥加密配置值, 输出的 "enc:..." 可直接粘贴到配置文件中,
+// 主密钥通过 -key 参数或 CONFIG_MASTER_KEY 环境变量提供
+//
+//	CONFIG_MASTER_KEY=change-this-pass go run ./cmd/configenc 123456
+//	echo -n 123456 | go run ./cmd/configenc -key change-this-pass
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/morgine/pkg/config"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	key := flag.String("key", os.Getenv(config.MasterKeyEnv), "主密钥(16, 24 或 32 位), 默认读取 "+config.MasterKeyEnv+" 环境变量")
+	flag.Parse()
+	if *key == "" {
+		exit(fmt.Errorf("需要提供主密钥"))
+	}
+	var value string
+	if flag.NArg() > 0 {
+		value = flag.Arg(0)
+	} else {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			exit(err)
+		}
+		value = string(data)
+	}
+	encrypted, err := config.EncryptValue(value, []byte(*key))
+	if err != nil {
+		exit(err)
+	}
+	fmt.Println(encrypted)
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,33 @@
+// configgen 根据代码中注册的配置结构生成带注释的示例 TOML 配置文件或 JSON Schema
+//
+//	go run ./cmd/configgen > config.toml
+//	go run ./cmd/configgen -schema > config.schema.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	_ "github.com/morgine/pkg/admin"
+	"github.com/morgine/pkg/config"
+	_ "github.com/morgine/pkg/database/mysql"
+	_ "github.com/morgine/pkg/database/orm"
+	_ "github.com/morgine/pkg/database/postgres"
+	_ "github.com/morgine/pkg/redis"
+)
+
+func main() {
+	schema := flag.Bool("schema", false, "生成 JSON Schema")
+	flag.Parse()
+	if *schema {
+		data, err := config.GenerateRegisteredJSONSchema()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(append(data, '\n'))
+	} else {
+		os.Stdout.Write(config.GenerateSampleTOML())
+	}
+}
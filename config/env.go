@@ -4,11 +4,15 @@ package config
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/BurntSushi/toml"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // OsEnvGetter provided OS environment values
@@ -35,23 +39,81 @@ func (e *LoadOsError) Error() string {
 
 type Configs map[string]interface{}
 
-// Unmarshal for decoding Configs data to schema
+// Unmarshal for decoding Configs data to schema, secret references such as "enc:..." of the fields
+// tagged `secret:"true"` are resolved, other values are kept as is, string values of boolean and
+// number fields are parsed to the field types, zero value fields are set by the `default` tags before
+// decoding, and schema is validated by the `validate` tags after decoding
 func (cs Configs) Unmarshal(schema interface{}) error {
-	buf := new(bytes.Buffer)
-	err := toml.NewEncoder(buf).Encode(cs)
+	return cs.unmarshal("", schema)
+}
+
+// UnmarshalSub is shorthand for GetSub and Unmarshal, field paths of validation errors are
+// prefixed by namespace
+func (cs Configs) UnmarshalSub(namespace string, schema interface{}) error {
+	envs, err := cs.GetSub(namespace)
 	if err != nil {
 		return err
 	}
-	return toml.Unmarshal(buf.Bytes(), schema)
+	return envs.unmarshal(namespace, schema)
 }
 
-// UnmarshalSub is shorthand for GetSub and Unmarshal
-func (cs Configs) UnmarshalSub(namespace string, schema interface{}) error {
-	envs, err := cs.GetSub(namespace)
+func (cs Configs) unmarshal(namespace string, schema interface{}) error {
+	return cs.decode(namespace, schema, false, nil)
+}
+
+// decode decodes cs to schema, if strict is true, undecoded keys are reported by a *StrictError
+// located by sources
+func (cs Configs) decode(namespace string, schema interface{}, strict bool, sources Sources) error {
+	err := ApplyDefaults(schema)
+	if err != nil {
+		return err
+	}
+	secrets := schemaSecrets(namespace, schema)
+	recordSecrets(secrets)
+	resolved, err := resolveSecrets(namespace, cs, secrets)
+	if err != nil {
+		return err
+	}
+	if rt := schemaStruct(schema); rt != nil {
+		err = coerceSchema(namespace, resolved, rt)
+		if err != nil {
+			return err
+		}
+	}
+	buf := new(bytes.Buffer)
+	err = toml.NewEncoder(buf).Encode(resolved)
+	if err != nil {
+		return err
+	}
+	md, err := toml.Decode(buf.String(), schema)
 	if err != nil {
 		return err
 	}
-	return envs.Unmarshal(schema)
+	if strict {
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			var paths []string
+			for _, key := range undecoded {
+				paths = append(paths, key.String())
+			}
+			// only report the leaf keys of unknown tables
+			leaves := make([]string, 0, len(paths))
+			for _, path := range paths {
+				isTable := false
+				for _, other := range paths {
+					if strings.HasPrefix(other, path+".") {
+						isTable = true
+						break
+					}
+				}
+				if !isTable {
+					leaves = append(leaves, path)
+				}
+			}
+			paths = leaves
+			return &StrictError{Reason: "unknown keys", Keys: unknownKeys(namespace, paths, sources)}
+		}
+	}
+	return ValidateNamespace(namespace, schema)
 }
 
 // GetSub returns sub environments and load os environment values
@@ -78,85 +140,146 @@ func (cs Configs) Len() int {
 	return len(cs)
 }
 
-func (cs Configs) GetStr(name string) string {
-	return cs[name].(string)
-}
-
-func (cs Configs) GetInt(name string) int {
-	return cs[name].(int)
-}
-
-func (cs Configs) GetFloat(name string) float64 {
-	return cs[name].(float64)
+// LoadOSEnv for loading the OS environment values, nested tables are loaded recursively with
+// the namespace "namespace.key"
+func (cs Configs) LoadOSEnv(namespace string) (err error) {
+	for key, value := range cs {
+		if table, ok := asTable(value); ok {
+			err = Configs(table).LoadOSEnv(joinPath(namespace, key))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		ov := OsEnvGetter(namespace, key)
+		if ov != "" {
+			value, err = parseEnvValue(value, ov)
+			if err != nil {
+				return &LoadOsError{
+					Namespace: namespace,
+					Key:       key,
+					err:       err,
+				}
+			}
+			cs[key] = value
+		}
+	}
+	return nil
 }
 
-func (cs Configs) GetBool(name string) bool {
-	return cs[name].(bool)
+// parseEnvValue parses the OS environment value ov to the type of the config value
+func parseEnvValue(value interface{}, ov string) (interface{}, error) {
+	switch vt := value.(type) {
+	case string:
+		return ov, nil
+	case int:
+		return strconv.Atoi(ov)
+	case int64:
+		return strconv.ParseInt(ov, 10, 64)
+	case bool:
+		switch ov {
+		case "y", "Y", "yes", "YES", "Yes", "1", "t", "T", "true", "TRUE", "True":
+			return true, nil
+		case "n", "N", "no", "NO", "No", "0", "f", "F", "false", "FALSE", "False":
+			return false, nil
+		default:
+			return nil, errors.New("need a boolean argument")
+		}
+	case float64:
+		return strconv.ParseFloat(ov, 64)
+	case time.Time:
+		return time.Parse(time.RFC3339Nano, ov)
+	case time.Duration:
+		return time.ParseDuration(ov)
+	case []interface{}:
+		return parseEnvList(vt, ov)
+	case []string, []int, []int64, []float64, []bool:
+		list, err := parseEnvList(toInterfaces(vt), ov)
+		if err != nil {
+			return nil, err
+		}
+		// keep the slice type of the config value
+		rv := reflect.MakeSlice(reflect.TypeOf(vt), len(list), len(list))
+		for i, elem := range list {
+			rv.Index(i).Set(reflect.ValueOf(elem))
+		}
+		return rv.Interface(), nil
+	default:
+		return nil, fmt.Errorf("not support the %v argument", vt)
+	}
 }
 
-func (cs Configs) GetSliceStr(name string) []string {
-	return cs[name].([]string)
+// parseEnvList parses a JSON list such as `[1, 2]` or a comma separated list such as `1,2`,
+// elements are parsed to the type of the first element of the config list, or string if the list is empty
+func parseEnvList(list []interface{}, ov string) ([]interface{}, error) {
+	var elems []string
+	if trimmed := strings.TrimSpace(ov); strings.HasPrefix(trimmed, "[") {
+		var raw []interface{}
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		err := decoder.Decode(&raw)
+		if err != nil {
+			return nil, err
+		}
+		for _, elem := range raw {
+			elems = append(elems, fmt.Sprint(elem))
+		}
+	} else if trimmed != "" {
+		for _, elem := range strings.Split(trimmed, ",") {
+			elems = append(elems, strings.TrimSpace(elem))
+		}
+	}
+	var typ interface{} = ""
+	if len(list) > 0 {
+		typ = list[0]
+	}
+	values := make([]interface{}, len(elems))
+	for i, elem := range elems {
+		value, err := parseEnvValue(typ, elem)
+		if err != nil {
+			return nil, fmt.Errorf("list element %d: %w", i, err)
+		}
+		values[i] = value
+	}
+	return values, nil
 }
 
-func (cs Configs) GetSliceInt(name string) []int {
-	return cs[name].([]int)
+func toInterfaces(slice interface{}) []interface{} {
+	rv := reflect.ValueOf(slice)
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	if len(list) == 0 {
+		// empty typed slice still needs the element type
+		list = append(list, reflect.Zero(rv.Type().Elem()).Interface())
+	}
+	return list
 }
 
-func (cs Configs) GetSliceFloat(name string) []float64 {
-	return cs[name].([]float64)
+// EnvKey maps namespace and key to an upper-case environment key joined by underscores,
+// e.g. EnvKey("APP", "mysql.replica", "host") returns "APP_MYSQL_REPLICA_HOST"
+func EnvKey(prefix, namespace, key string) string {
+	var parts []string
+	for _, part := range []string{prefix, namespace, key} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return envKeyReplacer.Replace(strings.ToUpper(strings.Join(parts, "_")))
 }
 
-func (cs Configs) GetSliceBool(name string) []bool {
-	return cs[name].([]bool)
-}
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
 
-// LoadOSEnv for loading the OS environment values, if namespace
-func (cs Configs) LoadOSEnv(namespace string) (err error) {
-	for key, value := range cs {
-		ov := OsEnvGetter(namespace, key)
-		if ov != "" {
-			switch vt := value.(type) {
-			case string:
-				cs[key] = ov
-			case int:
-				cs[key], err = strconv.Atoi(ov)
-				if err != nil {
-					return &LoadOsError{
-						Namespace: namespace,
-						Key:       key,
-						err:       err,
-					}
-				}
-			case bool:
-				switch ov {
-				case "y", "Y", "yes", "YES", "Yes", "1", "t", "T", "true", "TRUE", "True":
-					cs[key] = true
-				case "n", "N", "no", "NO", "No", "0", "f", "F", "false", "FALSE", "False":
-					cs[key] = false
-				default:
-					return &LoadOsError{
-						Namespace: namespace,
-						Key:       key,
-						err:       errors.New("need a boolean argument"),
-					}
-				}
-			case float64:
-				cs[key], err = strconv.ParseFloat(ov, 64)
-				if err != nil {
-					return &LoadOsError{
-						Namespace: namespace,
-						Key:       key,
-						err:       err,
-					}
-				}
-			default:
-				return &LoadOsError{
-					Namespace: namespace,
-					Key:       key,
-					err:       fmt.Errorf("not support the %v argument", vt),
-				}
-			}
+// PrefixedEnvGetter returns an OsEnvGetter which reads the dotted environment key such as
+// "mysql.host" first, and then the upper-case key with prefix such as "APP_MYSQL_HOST", e.g.
+//
+//	config.OsEnvGetter = config.PrefixedEnvGetter("APP")
+func PrefixedEnvGetter(prefix string) func(namespace, key string) string {
+	return func(namespace, key string) string {
+		if value := os.Getenv(joinPath(namespace, key)); value != "" {
+			return value
 		}
+		return os.Getenv(EnvKey(prefix, namespace, key))
 	}
-	return nil
 }
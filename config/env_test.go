@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigs_LoadOSEnv(t *testing.T) {
+	cs, err := UnmarshalMemory([]byte(`
+[mysql]
+port = 3306
+hosts = ["a", "b"]
+ports = [1, 2]
+timeout = 1979-05-27T07:32:00Z
+[mysql.replica]
+host = "127.0.0.1"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(getter func(namespace, key string) string) {
+		OsEnvGetter = getter
+	}(OsEnvGetter)
+	OsEnvGetter = PrefixedEnvGetter("APP")
+	var envs = map[string]string{
+		"APP_MYSQL_PORT":         "3307",
+		"mysql.hosts":            "c, d",
+		"APP_MYSQL_PORTS":        "[3, 4]",
+		"APP_MYSQL_TIMEOUT":      "2020-01-02T03:04:05Z",
+		"APP_MYSQL_REPLICA_HOST": "localhost",
+	}
+	for key, value := range envs {
+		err = os.Setenv(key, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Unsetenv(key)
+	}
+	sub, err := cs.GetSub("mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	need := Configs{
+		"port":    int64(3307),
+		"hosts":   []interface{}{"c", "d"},
+		"ports":   []interface{}{int64(3), int64(4)},
+		"timeout": time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"replica": map[string]interface{}{
+			"host": "localhost",
+		},
+	}
+	if !reflect.DeepEqual(need, sub) {
+		t.Errorf("need: %v, got: %v\n", need, sub)
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	if got := EnvKey("APP", "mysql.replica", "max-conns"); got != "APP_MYSQL_REPLICA_MAX_CONNS" {
+		t.Errorf("need: APP_MYSQL_REPLICA_MAX_CONNS, got: %s\n", got)
+	}
+}
+
+func TestUnmarshalCoerce(t *testing.T) {
+	cs := FromKeyValues(map[string]string{
+		"mysql.port":     "3307",
+		"mysql.debug":    "true",
+		"mysql.ratio":    "0.5",
+		"mysql.timeout":  "1m30s",
+		"mysql.ports":    "1, 2",
+		"mysql.password": "123456",
+	})
+	cs.Get("mysql").(map[string]interface{})["replicas"] = []interface{}{map[string]interface{}{"port": "3308"}}
+	var mysql struct {
+		Port     int           `toml:"port"`
+		Debug    bool          `toml:"debug"`
+		Ratio    float64       `toml:"ratio"`
+		Timeout  time.Duration `toml:"timeout"`
+		Ports    []int         `toml:"ports"`
+		Password string        `toml:"password"`
+		Replicas []struct {
+			Port uint16 `toml:"port"`
+		} `toml:"replicas"`
+	}
+	err := cs.UnmarshalSub("mysql", &mysql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mysql.Port != 3307 || !mysql.Debug || mysql.Ratio != 0.5 || mysql.Timeout != 90*time.Second ||
+		!reflect.DeepEqual(mysql.Ports, []int{1, 2}) || mysql.Password != "123456" ||
+		len(mysql.Replicas) != 1 || mysql.Replicas[0].Port != 3308 {
+		t.Errorf("got: %+v\n", mysql)
+	}
+	if got := cs.Get("mysql.port"); got != "3307" {
+		t.Errorf("configs should not be modified, got: %v\n", got)
+	}
+
+	err = Configs{"mysql": map[string]interface{}{"port": "abc"}}.UnmarshalSub("mysql", &mysql)
+	if err == nil || !strings.Contains(err.Error(), "mysql.port") {
+		t.Errorf("need error of mysql.port, got: %v\n", err)
+	}
+}
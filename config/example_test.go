@@ -3,6 +3,7 @@
 package config
 
 import (
+	"flag"
 	"fmt"
 	"os"
 )
@@ -40,3 +41,39 @@ port= "3306"
 	// Output:
 	// localhost:3306
 }
+
+func ExampleLoader_AddFlags() {
+	type Mysql struct {
+		Host string `toml:"host" default:"127.0.0.1" desc:"连接地址"`
+		Port int    `toml:"port" default:"3306" desc:"连接端口"`
+	}
+
+	// flags are generated from the config struct, "--help" prints the desc tags
+	fs := flag.NewFlagSet("app", flag.ExitOnError)
+	BindFlags(fs, "mysql", &Mysql{})
+	err := fs.Parse([]string{"--mysql.port", "3307"})
+	if err != nil {
+		panic(err)
+	}
+
+	base, err := UnmarshalMemory([]byte(`
+[mysql]
+host = "localhost"
+`))
+	if err != nil {
+		panic(err)
+	}
+	configs, _, err := NewLoader().Add("base", base).AddFlags(fs).Load()
+	if err != nil {
+		panic(err)
+	}
+
+	mysql := &Mysql{}
+	err = configs.UnmarshalSub("mysql", mysql)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%s:%d", mysql.Host, mysql.Port)
+	// Output:
+	// localhost:3307
+}
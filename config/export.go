@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the secret values of exported configs
+const Redacted = "******"
+
+// SecretNames are the key names of secret values, a key is secret if its lower-case name contains any
+// of them, fields tagged `secret:"true"` of the registered schemas and of the schemas which namespaces
+// are decoded to are secret too
+var SecretNames = []string{"password", "passwd", "secret", "token", "aes_crypt_key", "master_key", "private_key"}
+
+// decodedSecrets keeps the secret key paths of the schemas decoded by Unmarshal and UnmarshalSub, so the
+// namespaces which are not registered, such as "mysql_read" of a second mysql config, are redacted too
+var decodedSecrets = struct {
+	sync.RWMutex
+	m map[string]bool
+}{
+	m: make(map[string]bool),
+}
+
+// recordSecrets records the secret key paths of a decoded schema
+func recordSecrets(secrets map[string]bool) {
+	decodedSecrets.Lock()
+	defer decodedSecrets.Unlock()
+	for path := range secrets {
+		decodedSecrets.m[path] = true
+	}
+}
+
+// Redact returns a deep copy of cs in which every secret value is replaced by Redacted
+func Redact(cs Configs) Configs {
+	secrets := make(map[string]bool)
+	for _, s := range Schemas() {
+		secretPaths(s.Namespace, s.Type, secrets)
+	}
+	decodedSecrets.RLock()
+	for path := range decodedSecrets.m {
+		secrets[path] = true
+	}
+	decodedSecrets.RUnlock()
+	return redact("", cs, secrets)
+}
+
+func redact(prefix string, table map[string]interface{}, secrets map[string]bool) map[string]interface{} {
+	m := make(map[string]interface{}, len(table))
+	for key, value := range table {
+		path := joinPath(prefix, key)
+		if sub, ok := asTable(value); ok {
+			m[key] = redact(path, sub, secrets)
+		} else if tables, ok := asTables(value); ok {
+			// the tables of an array share the key paths of the array
+			redacted := make([]map[string]interface{}, len(tables))
+			for i, sub := range tables {
+				redacted[i] = redact(path, sub, secrets)
+			}
+			m[key] = redacted
+		} else if secrets[path] || isSecretName(key) {
+			m[key] = Redacted
+		} else {
+			m[key] = value
+		}
+	}
+	return m
+}
+
+// asTables returns the tables of an array of tables
+func asTables(value interface{}) ([]map[string]interface{}, bool) {
+	switch tv := value.(type) {
+	case []map[string]interface{}:
+		return tv, true
+	case []interface{}:
+		if len(tv) == 0 {
+			return nil, false
+		}
+		tables := make([]map[string]interface{}, len(tv))
+		for i, elem := range tv {
+			table, ok := asTable(elem)
+			if !ok {
+				return nil, false
+			}
+			tables[i] = table
+		}
+		return tables, true
+	default:
+		return nil, false
+	}
+}
+
+func isSecretName(key string) bool {
+	key = strings.ToLower(key)
+	for _, name := range SecretNames {
+		if strings.Contains(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretPaths collects the key paths of fields tagged `secret:"true"`
+func secretPaths(prefix string, rt reflect.Type, secrets map[string]bool) {
+	for _, field := range schemaFields(rt) {
+		path := joinPath(prefix, fieldName(field))
+		if field.Tag.Get("secret") == "true" {
+			secrets[path] = true
+		}
+		switch rt := field.Type; {
+		case isTable(rt):
+			secretPaths(path, rt, secrets)
+		case rt.Kind() == reflect.Slice && isTable(rt.Elem()):
+			secretPaths(path, rt.Elem(), secrets)
+		}
+	}
+}
+
+// Export encodes the redacted cs in format
+func Export(cs Configs, format Format) ([]byte, error) {
+	redacted := Redact(cs)
+	switch format {
+	case TOML, "":
+		buf := new(bytes.Buffer)
+		err := toml.NewEncoder(buf).Encode(redacted)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case JSON:
+		return json.MarshalIndent(redacted, "", "  ")
+	case YAML:
+		return yaml.Marshal(redacted)
+	default:
+		return nil, fmt.Errorf("config: unsupported format %q", format)
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	type replica struct {
+		DSN string `toml:"dsn" secret:"true"`
+	}
+	RegisterSchema("export_test", "", struct {
+		DSN      string    `toml:"dsn" secret:"true"`
+		Replicas []replica `toml:"replicas"`
+	}{})
+	cs, err := UnmarshalMemory([]byte(`
+[export_test]
+dsn = "root:123456@tcp(127.0.0.1:3306)/db"
+host = "127.0.0.1"
+[export_test.admin]
+aes_crypt_key = "change this pass"
+[[export_test.replicas]]
+dsn = "root:654321@tcp(127.0.0.2:3306)/db"
+[[export_test.replicas]]
+dsn = "root:654321@tcp(127.0.0.3:3306)/db"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []Format{TOML, JSON, YAML} {
+		data, err := Export(cs, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := string(data); strings.Contains(s, "123456") || strings.Contains(s, "654321") || strings.Contains(s, "change this pass") || !strings.Contains(s, "127.0.0.1") {
+			t.Errorf("%s: got: %s\n", format, s)
+		}
+	}
+	if cs.GetStr("export_test.admin.aes_crypt_key") != "change this pass" {
+		t.Error("configs should not be modified")
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/configs", ExportHandler(func() Configs { return cs }))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/configs", nil))
+	var got Configs
+	err = json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn := got.GetStr("export_test.dsn"); dsn != Redacted {
+		t.Errorf("need: %s, got: %s\n", Redacted, dsn)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/configs?format=xml", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("need: 400, got: %d\n", w.Code)
+	}
+}
+
+func TestRedactDecodedNamespace(t *testing.T) {
+	cs, err := UnmarshalMemory([]byte(`
+[export_test_read]
+dsn = "reader:123456@tcp(127.0.0.2:3306)/db"
+host = "127.0.0.2"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		DSN  string `toml:"dsn" secret:"true"`
+		Host string `toml:"host"`
+	}
+	err = cs.UnmarshalSub("export_test_read", &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redacted := Redact(cs)
+	if dsn := redacted.GetStr("export_test_read.dsn"); dsn != Redacted {
+		t.Errorf("need: %s, got: %s\n", Redacted, dsn)
+	}
+	if host := redacted.GetStr("export_test_read.host"); host != "127.0.0.2" {
+		t.Errorf("need: 127.0.0.2, got: %s\n", host)
+	}
+}
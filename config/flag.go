@@ -0,0 +1,160 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindFlags defines a flag named "namespace.key" on fs for every field of schema, nested structs
+// are bound recursively, such as "--mysql.host" and "--mysql.replica.host". The usage of a flag is
+// the `desc` tag and the default value shown by "--help" is the `default` tag. Flags only override
+// configs if they are set on the command line, see FlagConfigs.
+func BindFlags(fs *flag.FlagSet, namespace string, schema interface{}) {
+	rt := reflect.TypeOf(schema)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	bindFlags(fs, namespace, rt)
+}
+
+// BindRegisteredFlags binds the flags of all registered schemas
+func BindRegisteredFlags(fs *flag.FlagSet) {
+	for _, s := range Schemas() {
+		bindFlags(fs, s.Namespace, s.Type)
+	}
+}
+
+func bindFlags(fs *flag.FlagSet, namespace string, rt reflect.Type) {
+	for _, field := range schemaFields(rt) {
+		name := joinPath(namespace, fieldName(field))
+		if isTable(field.Type) {
+			bindFlags(fs, name, field.Type)
+			continue
+		}
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		value := &flagValue{typ: field.Type}
+		if def, ok := field.Tag.Lookup("default"); ok {
+			value.raw = def
+		}
+		fs.Var(value, name, field.Tag.Get("desc"))
+	}
+}
+
+// flagValue keeps the raw command line value, the value is parsed to the field type when it is set
+type flagValue struct {
+	typ   reflect.Type
+	raw   string
+	value interface{}
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.raw
+}
+
+func (v *flagValue) Set(s string) error {
+	value, err := parseFlag(v.typ, s)
+	if err != nil {
+		return err
+	}
+	v.raw, v.value = s, value
+	return nil
+}
+
+func (v *flagValue) Get() interface{} {
+	return v.value
+}
+
+// IsBoolFlag allows "--namespace.key" without value for boolean fields
+func (v *flagValue) IsBoolFlag() bool {
+	return v.typ.Kind() == reflect.Bool
+}
+
+// parseFlag parses s to the value decoded by TOML for the field type
+func parseFlag(typ reflect.Type, s string) (interface{}, error) {
+	if typ == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		return int64(d), nil
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return s, nil
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, typ.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, typ.Bits())
+		if err != nil {
+			return nil, err
+		}
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("integer %d overflows int64", u)
+		}
+		return int64(u), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, typ.Bits())
+	case reflect.Slice:
+		list := []interface{}{}
+		if strings.TrimSpace(s) == "" {
+			return list, nil
+		}
+		for _, elem := range strings.Split(s, ",") {
+			value, err := parseFlag(typ.Elem(), strings.TrimSpace(elem))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case reflect.Struct:
+		if typ == reflect.TypeOf(time.Time{}) {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+	}
+	return nil, fmt.Errorf("not support the %s type", typ)
+}
+
+// FlagConfigs returns the configs of the flags which are set on the command line, flag names are used as
+// dotted key paths, fs must be parsed
+func FlagConfigs(fs *flag.FlagSet) Configs {
+	cs := make(Configs)
+	fs.Visit(func(f *flag.Flag) {
+		if !strings.Contains(f.Name, ".") {
+			return
+		}
+		var value interface{} = f.Value.String()
+		if getter, ok := f.Value.(flag.Getter); ok {
+			value = getter.Get()
+		}
+		setPath(cs, f.Name, value)
+	})
+	return cs
+}
+
+// AddFlags pushes the flags which are set on the command line, fs must be parsed before Load is called,
+// flags should be the last layer to take the highest precedence
+func (l *Loader) AddFlags(fs *flag.FlagSet) *Loader {
+	l.layers = append(l.layers, layer{
+		name: "flags",
+		load: func(Configs) (Configs, Sources, error) {
+			if !fs.Parsed() {
+				return nil, nil, fmt.Errorf("flag set %s is not parsed", fs.Name())
+			}
+			return FlagConfigs(fs), nil, nil
+		},
+	})
+	return l
+}
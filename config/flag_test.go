@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBindFlags(t *testing.T) {
+	type schema struct {
+		Host    string   `toml:"host" default:"127.0.0.1" desc:"连接地址"`
+		Port    int      `toml:"port" default:"3306" desc:"连接端口"`
+		Debug   bool     `toml:"debug"`
+		Tags    []string `toml:"tags"`
+		Replica struct {
+			Host string `toml:"host"`
+		} `toml:"replica"`
+	}
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	BindFlags(fs, "mysql", &schema{})
+	err := fs.Parse([]string{"--mysql.port=3307", "--mysql.debug", "--mysql.tags", "a,b", "--mysql.replica.host", "10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := UnmarshalMemory([]byte(`
+[mysql]
+host = "localhost"
+port = 3306
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, sources, err := NewLoader().Add("base", base).AddFlags(fs).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &schema{}
+	err = cs.UnmarshalSub("mysql", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Host != "localhost" || s.Port != 3307 || !s.Debug || len(s.Tags) != 2 || s.Replica.Host != "10.0.0.2" {
+		t.Errorf("got: %+v\n", s)
+	}
+	if got := sources.Of("mysql.port"); got != "flags" {
+		t.Errorf("need: flags, got: %s\n", got)
+	}
+
+	buf := new(bytes.Buffer)
+	fs.SetOutput(buf)
+	fs.PrintDefaults()
+	if usage := buf.String(); !strings.Contains(usage, "-mysql.host") || !strings.Contains(usage, "连接地址 (default 127.0.0.1)") {
+		t.Errorf("got usage: %s\n", usage)
+	}
+
+	fs = flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(buf)
+	BindFlags(fs, "mysql", &schema{})
+	err = fs.Parse([]string{"--mysql.port=abc"})
+	if err == nil || !strings.Contains(err.Error(), "mysql.port") {
+		t.Errorf("need parse error of mysql.port, got: %v\n", err)
+	}
+}
+
+func TestParseFlag(t *testing.T) {
+	if _, err := parseFlag(reflect.TypeOf(uint64(0)), "18446744073709551615"); err == nil || !strings.Contains(err.Error(), "overflows") {
+		t.Errorf("need overflow error, got: %v\n", err)
+	}
+	value, err := parseFlag(reflect.TypeOf(uint64(0)), "9223372036854775807")
+	if err != nil || value != int64(9223372036854775807) {
+		t.Errorf("need: 9223372036854775807, got: %v %v\n", value, err)
+	}
+	value, err = parseFlag(reflect.TypeOf([]string{}), "")
+	if list, ok := value.([]interface{}); err != nil || !ok || len(list) != 0 {
+		t.Errorf("need empty list, got: %#v %v\n", value, err)
+	}
+}
@@ -0,0 +1,514 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ErrKeyNotFound is returned by the Lookup methods if the key path does not exist
+var ErrKeyNotFound = errors.New("key not found")
+
+// PathError is returned by the Lookup methods if the value is missing or can not be converted
+type PathError struct {
+	Path string // dotted key path
+	err  error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("config: %s: %s", e.Path, e.err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.err
+}
+
+// Lookup returns the value of the dotted key path, such as "mysql.replica.port", if the key path
+// does not exist, a *PathError wrapping ErrKeyNotFound is returned
+func (cs Configs) Lookup(path string) (interface{}, error) {
+	value, ok := lookupPath(cs, path)
+	if !ok {
+		return nil, &PathError{Path: path, err: ErrKeyNotFound}
+	}
+	return value, nil
+}
+
+// Get returns the value of the dotted key path, or nil if the key path does not exist
+func (cs Configs) Get(path string) interface{} {
+	value, _ := lookupPath(cs, path)
+	return value
+}
+
+// Has reports whether the dotted key path exists
+func (cs Configs) Has(path string) bool {
+	_, ok := lookupPath(cs, path)
+	return ok
+}
+
+// LookupStr returns the string value, numbers and booleans are formatted
+func (cs Configs) LookupStr(path string) (string, error) {
+	value, err := cs.lookup(path, toString)
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+// LookupInt returns the int value, int64, integral float and numeric string values are converted
+// if no precision is lost
+func (cs Configs) LookupInt(path string) (int, error) {
+	value, err := cs.lookup(path, toInt)
+	if err != nil {
+		return 0, err
+	}
+	return value.(int), nil
+}
+
+// LookupInt64 is the same as LookupInt but returns int64
+func (cs Configs) LookupInt64(path string) (int64, error) {
+	value, err := cs.lookup(path, toInt64)
+	if err != nil {
+		return 0, err
+	}
+	return value.(int64), nil
+}
+
+// LookupFloat returns the float64 value, integers and numeric strings are converted
+func (cs Configs) LookupFloat(path string) (float64, error) {
+	value, err := cs.lookup(path, toFloat)
+	if err != nil {
+		return 0, err
+	}
+	return value.(float64), nil
+}
+
+// LookupBool returns the bool value, strings such as "true", "yes", "1" are converted
+func (cs Configs) LookupBool(path string) (bool, error) {
+	value, err := cs.lookup(path, toBool)
+	if err != nil {
+		return false, err
+	}
+	return value.(bool), nil
+}
+
+// LookupDuration returns the time.Duration value, strings are parsed by time.ParseDuration,
+// such as "1m30s", and integers are treated as seconds
+func (cs Configs) LookupDuration(path string) (time.Duration, error) {
+	value, err := cs.lookup(path, toDuration)
+	if err != nil {
+		return 0, err
+	}
+	return value.(time.Duration), nil
+}
+
+// LookupTime returns the time.Time value, strings are parsed in RFC3339 or "2006-01-02" layout,
+// and integers are treated as unix seconds
+func (cs Configs) LookupTime(path string) (time.Time, error) {
+	value, err := cs.lookup(path, toTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return value.(time.Time), nil
+}
+
+// LookupSliceStr returns the []string value, elements are converted as LookupStr
+func (cs Configs) LookupSliceStr(path string) ([]string, error) {
+	var list []string
+	err := cs.lookupSlice(path, func(elem interface{}) error {
+		s, err := toString(elem)
+		if err == nil {
+			list = append(list, s.(string))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// LookupSliceInt returns the []int value, elements are converted as LookupInt
+func (cs Configs) LookupSliceInt(path string) ([]int, error) {
+	var list []int
+	err := cs.lookupSlice(path, func(elem interface{}) error {
+		i, err := toInt(elem)
+		if err == nil {
+			list = append(list, i.(int))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// LookupSliceFloat returns the []float64 value, elements are converted as LookupFloat
+func (cs Configs) LookupSliceFloat(path string) ([]float64, error) {
+	var list []float64
+	err := cs.lookupSlice(path, func(elem interface{}) error {
+		f, err := toFloat(elem)
+		if err == nil {
+			list = append(list, f.(float64))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// LookupSliceBool returns the []bool value, elements are converted as LookupBool
+func (cs Configs) LookupSliceBool(path string) ([]bool, error) {
+	var list []bool
+	err := cs.lookupSlice(path, func(elem interface{}) error {
+		b, err := toBool(elem)
+		if err == nil {
+			list = append(list, b.(bool))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetStr returns the string value, or "" if the value is missing or invalid
+func (cs Configs) GetStr(path string) string {
+	value, _ := cs.LookupStr(path)
+	return value
+}
+
+// GetInt returns the int value, or 0 if the value is missing or invalid
+func (cs Configs) GetInt(path string) int {
+	value, _ := cs.LookupInt(path)
+	return value
+}
+
+// GetInt64 returns the int64 value, or 0 if the value is missing or invalid
+func (cs Configs) GetInt64(path string) int64 {
+	value, _ := cs.LookupInt64(path)
+	return value
+}
+
+// GetFloat returns the float64 value, or 0 if the value is missing or invalid
+func (cs Configs) GetFloat(path string) float64 {
+	value, _ := cs.LookupFloat(path)
+	return value
+}
+
+// GetBool returns the bool value, or false if the value is missing or invalid
+func (cs Configs) GetBool(path string) bool {
+	value, _ := cs.LookupBool(path)
+	return value
+}
+
+// GetDuration returns the time.Duration value, or 0 if the value is missing or invalid
+func (cs Configs) GetDuration(path string) time.Duration {
+	value, _ := cs.LookupDuration(path)
+	return value
+}
+
+// GetTime returns the time.Time value, or the zero time if the value is missing or invalid
+func (cs Configs) GetTime(path string) time.Time {
+	value, _ := cs.LookupTime(path)
+	return value
+}
+
+// GetSliceStr returns the []string value, or nil if the value is missing or invalid
+func (cs Configs) GetSliceStr(path string) []string {
+	value, _ := cs.LookupSliceStr(path)
+	return value
+}
+
+// GetSliceInt returns the []int value, or nil if the value is missing or invalid
+func (cs Configs) GetSliceInt(path string) []int {
+	value, _ := cs.LookupSliceInt(path)
+	return value
+}
+
+// GetSliceFloat returns the []float64 value, or nil if the value is missing or invalid
+func (cs Configs) GetSliceFloat(path string) []float64 {
+	value, _ := cs.LookupSliceFloat(path)
+	return value
+}
+
+// GetSliceBool returns the []bool value, or nil if the value is missing or invalid
+func (cs Configs) GetSliceBool(path string) []bool {
+	value, _ := cs.LookupSliceBool(path)
+	return value
+}
+
+// GetStrOr returns the string value, or def if the value is missing or invalid
+func (cs Configs) GetStrOr(path string, def string) string {
+	value, err := cs.LookupStr(path)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetIntOr returns the int value, or def if the value is missing or invalid
+func (cs Configs) GetIntOr(path string, def int) int {
+	value, err := cs.LookupInt(path)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetInt64Or returns the int64 value, or def if the value is missing or invalid
+func (cs Configs) GetInt64Or(path string, def int64) int64 {
+	value, err := cs.LookupInt64(path)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetFloatOr returns the float64 value, or def if the value is missing or invalid
+func (cs Configs) GetFloatOr(path string, def float64) float64 {
+	value, err := cs.LookupFloat(path)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetBoolOr returns the bool value, or def if the value is missing or invalid
+func (cs Configs) GetBoolOr(path string, def bool) bool {
+	value, err := cs.LookupBool(path)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetDurationOr returns the time.Duration value, or def if the value is missing or invalid
+func (cs Configs) GetDurationOr(path string, def time.Duration) time.Duration {
+	value, err := cs.LookupDuration(path)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetTimeOr returns the time.Time value, or def if the value is missing or invalid
+func (cs Configs) GetTimeOr(path string, def time.Time) time.Time {
+	value, err := cs.LookupTime(path)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetSliceStrOr returns the []string value, or def if the value is missing or invalid
+func (cs Configs) GetSliceStrOr(path string, def []string) []string {
+	value, err := cs.LookupSliceStr(path)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetSliceIntOr returns the []int value, or def if the value is missing or invalid
+func (cs Configs) GetSliceIntOr(path string, def []int) []int {
+	value, err := cs.LookupSliceInt(path)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+func (cs Configs) lookup(path string, convert func(value interface{}) (interface{}, error)) (interface{}, error) {
+	value, err := cs.Lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	value, err = convert(value)
+	if err != nil {
+		return nil, &PathError{Path: path, err: err}
+	}
+	return value, nil
+}
+
+func (cs Configs) lookupSlice(path string, add func(elem interface{}) error) error {
+	value, err := cs.Lookup(path)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return &PathError{Path: path, err: fmt.Errorf("need a list, got %T", value)}
+	}
+	for i := 0; i < rv.Len(); i++ {
+		err = add(rv.Index(i).Interface())
+		if err != nil {
+			return &PathError{Path: fmt.Sprintf("%s[%d]", path, i), err: err}
+		}
+	}
+	return nil
+}
+
+func toString(value interface{}) (interface{}, error) {
+	switch tv := value.(type) {
+	case string:
+		return tv, nil
+	case []byte:
+		return string(tv), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return fmt.Sprint(tv), nil
+	case time.Duration:
+		return tv.String(), nil
+	case time.Time:
+		return tv.Format(time.RFC3339Nano), nil
+	default:
+		return "", fmt.Errorf("need a string, got %T", value)
+	}
+}
+
+func toInt64(value interface{}) (interface{}, error) {
+	switch tv := value.(type) {
+	case int:
+		return int64(tv), nil
+	case int8:
+		return int64(tv), nil
+	case int16:
+		return int64(tv), nil
+	case int32:
+		return int64(tv), nil
+	case int64:
+		return tv, nil
+	case uint8:
+		return int64(tv), nil
+	case uint16:
+		return int64(tv), nil
+	case uint32:
+		return int64(tv), nil
+	case uint:
+		if uint64(tv) > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows int64", tv)
+		}
+		return int64(tv), nil
+	case uint64:
+		if tv > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows int64", tv)
+		}
+		return int64(tv), nil
+	case float32:
+		return toInt64(float64(tv))
+	case float64:
+		if tv != math.Trunc(tv) || tv < math.MinInt64 || tv >= math.MaxInt64 {
+			return nil, fmt.Errorf("%v is not an integer", tv)
+		}
+		return int64(tv), nil
+	case time.Duration:
+		return int64(tv), nil
+	case string:
+		i, err := strconv.ParseInt(tv, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", tv)
+		}
+		return i, nil
+	default:
+		return nil, fmt.Errorf("need an integer, got %T", value)
+	}
+}
+
+func toInt(value interface{}) (interface{}, error) {
+	i, err := toInt64(value)
+	if err != nil {
+		return nil, err
+	}
+	i64 := i.(int64)
+	if int64(int(i64)) != i64 {
+		return nil, fmt.Errorf("%d overflows int", i64)
+	}
+	return int(i64), nil
+}
+
+func toFloat(value interface{}) (interface{}, error) {
+	switch tv := value.(type) {
+	case float64:
+		return tv, nil
+	case float32:
+		return float64(tv), nil
+	case string:
+		f, err := strconv.ParseFloat(tv, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", tv)
+		}
+		return f, nil
+	default:
+		i, err := toInt64(value)
+		if err != nil {
+			return nil, fmt.Errorf("need a number, got %T", value)
+		}
+		n := i.(int64)
+		f := float64(n)
+		// integers above 2^53 may not be represented exactly
+		if f >= math.MaxInt64 || int64(f) != n {
+			return nil, fmt.Errorf("%d loses precision as float64", n)
+		}
+		return f, nil
+	}
+}
+
+func toBool(value interface{}) (interface{}, error) {
+	switch tv := value.(type) {
+	case bool:
+		return tv, nil
+	case string:
+		b, err := parseEnvValue(false, tv)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", tv)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("need a boolean, got %T", value)
+	}
+}
+
+func toDuration(value interface{}) (interface{}, error) {
+	switch tv := value.(type) {
+	case time.Duration:
+		return tv, nil
+	case string:
+		d, err := time.ParseDuration(tv)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	default:
+		i, err := toInt64(value)
+		if err != nil {
+			return nil, fmt.Errorf("need a duration, got %T", value)
+		}
+		return time.Duration(i.(int64)) * time.Second, nil
+	}
+}
+
+func toTime(value interface{}) (interface{}, error) {
+	switch tv := value.(type) {
+	case time.Time:
+		return tv, nil
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, tv); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a time", tv)
+	default:
+		i, err := toInt64(value)
+		if err != nil {
+			return nil, fmt.Errorf("need a time, got %T", value)
+		}
+		return time.Unix(i.(int64), 0), nil
+	}
+}
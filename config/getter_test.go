@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigs_Getters(t *testing.T) {
+	cs, err := UnmarshalMemory([]byte(`
+[mysql]
+host = "127.0.0.1"
+timeout = "1m30s"
+lifetime = 60
+ratio = 0.5
+debug = "yes"
+created = 1979-05-27T07:32:00Z
+ports = [3306, 3307]
+big = 9007199254740993
+hosts = ["a", "b"]
+[mysql.replica]
+port = 3308
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cs.GetInt("mysql.replica.port"); got != 3308 {
+		t.Errorf("need: 3308, got: %d\n", got)
+	}
+	if got := cs.GetStr("mysql.replica.port"); got != "3308" {
+		t.Errorf("need: 3308, got: %s\n", got)
+	}
+	if got := cs.GetDuration("mysql.timeout"); got != 90*time.Second {
+		t.Errorf("need: 1m30s, got: %s\n", got)
+	}
+	if got := cs.GetDuration("mysql.lifetime"); got != time.Minute {
+		t.Errorf("need: 1m0s, got: %s\n", got)
+	}
+	if got := cs.GetFloat("mysql.ratio"); got != 0.5 {
+		t.Errorf("need: 0.5, got: %v\n", got)
+	}
+	if got := cs.GetBool("mysql.debug"); !got {
+		t.Errorf("need: true, got: %v\n", got)
+	}
+	if got := cs.GetTime("mysql.created"); got.Year() != 1979 {
+		t.Errorf("need: 1979, got: %v\n", got)
+	}
+	if got := cs.GetSliceInt("mysql.ports"); !reflect.DeepEqual(got, []int{3306, 3307}) {
+		t.Errorf("need: [3306 3307], got: %v\n", got)
+	}
+	if got := cs.GetSliceStr("mysql.hosts"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("need: [a b], got: %v\n", got)
+	}
+	if got := cs.GetIntOr("mysql.port", 3306); got != 3306 {
+		t.Errorf("need: 3306, got: %d\n", got)
+	}
+	if got := cs.GetIntOr("mysql.host", 3306); got != 3306 {
+		t.Errorf("need: 3306, got: %d\n", got)
+	}
+	if _, err = cs.LookupInt("mysql.port"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("need ErrKeyNotFound, got: %v\n", err)
+	}
+	if _, err = cs.LookupInt("mysql.ratio"); err == nil {
+		t.Error("need error of lossy conversion")
+	}
+	if got := cs.GetFloat("mysql.replica.port"); got != 3308 {
+		t.Errorf("need: 3308, got: %v\n", got)
+	}
+	if _, err = cs.LookupFloat("mysql.big"); err == nil {
+		t.Error("need error of lossy conversion")
+	}
+	if _, err = cs.LookupSliceInt("mysql.hosts"); err == nil || err.(*PathError).Path != "mysql.hosts[0]" {
+		t.Errorf("need error of mysql.hosts[0], got: %v\n", err)
+	}
+}
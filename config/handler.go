@@ -0,0 +1,32 @@
+package config
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+var contentTypes = map[Format]string{
+	TOML: "application/toml; charset=utf-8",
+	JSON: "application/json; charset=utf-8",
+	YAML: "application/x-yaml; charset=utf-8",
+}
+
+// ExportHandler serves the redacted effective configs returned by get, such as Watcher.Configs,
+// the format is chosen by the "format" query parameter (toml, json or yaml), default is JSON.
+// The handler exposes the deployment details, so it must be registered behind the admin authentication.
+func ExportHandler(get func() Configs) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		format := Format(ctx.DefaultQuery("format", string(JSON)))
+		contentType, ok := contentTypes[format]
+		if !ok {
+			ctx.String(http.StatusBadRequest, "unsupported format %q", format)
+			return
+		}
+		data, err := Export(get(), format)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "%s", err)
+			return
+		}
+		ctx.Data(http.StatusOK, contentType, data)
+	}
+}
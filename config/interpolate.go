@@ -0,0 +1,209 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// InterpolateError is returned if a "${...}" reference can not be resolved
+type InterpolateError struct {
+	File string // file which supplied the key, empty if unknown
+	Key  string // dotted key path which contains the reference
+	err  error
+}
+
+func (e *InterpolateError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("config: interpolate %s in %s error: %s", e.Key, e.File, e.err)
+	}
+	return fmt.Sprintf("config: interpolate %s error: %s", e.Key, e.err)
+}
+
+func (e *InterpolateError) Unwrap() error {
+	return e.err
+}
+
+// Interpolate resolves the references in string values of cs in place:
+//
+//	${namespace.key}        value of the dotted key path of cs
+//	${ENV_VAR}              OS environment value, if no key path matches
+//	${ENV_VAR:-default}     same as above, default is used if the value is empty
+//	$${                     escaped literal "${"
+//
+// a string which is exactly one reference keeps the type of the referenced value, such as integer,
+// references are resolved recursively and cycles are reported as errors
+func Interpolate(cs Configs) error {
+	return interpolate(cs, nil)
+}
+
+func interpolate(cs Configs, sources Sources) error {
+	ip := &interpolator{
+		cs:       cs,
+		sources:  sources,
+		resolved: make(map[string]bool),
+		visiting: make(map[string]bool),
+	}
+	for key := range cs {
+		_, err := ip.value(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type interpolator struct {
+	cs       Configs
+	sources  Sources
+	resolved map[string]bool
+	visiting map[string]bool
+	stack    []string
+}
+
+// value resolves and returns the value of path
+func (ip *interpolator) value(path string) (interface{}, error) {
+	value, ok := lookupPath(ip.cs, path)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if ip.resolved[path] {
+		return value, nil
+	}
+	if ip.visiting[path] {
+		return nil, fmt.Errorf("reference cycle %s -> %s", strings.Join(ip.stack, " -> "), path)
+	}
+	ip.visiting[path] = true
+	ip.stack = append(ip.stack, path)
+	defer func() {
+		delete(ip.visiting, path)
+		ip.stack = ip.stack[:len(ip.stack)-1]
+	}()
+	if table, ok := asTable(value); ok {
+		for key := range table {
+			_, err := ip.value(joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		resolved, err := ip.expand(path, value)
+		if err != nil {
+			var ie *InterpolateError
+			if errors.As(err, &ie) {
+				return nil, err
+			}
+			return nil, &InterpolateError{File: ip.sources.Of(path), Key: path, err: err}
+		}
+		setPath(ip.cs, path, resolved)
+		value = resolved
+	}
+	ip.resolved[path] = true
+	return value, nil
+}
+
+func (ip *interpolator) expand(path string, value interface{}) (interface{}, error) {
+	switch tv := value.(type) {
+	case string:
+		return ip.expandString(tv)
+	case []interface{}:
+		list := make([]interface{}, len(tv))
+		for i, elem := range tv {
+			v, err := ip.expand(path, elem)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case []map[string]interface{}:
+		// arrays of tables of TOML
+		list := make([]map[string]interface{}, len(tv))
+		for i, table := range tv {
+			v, err := ip.expand(path, table)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v.(map[string]interface{})
+		}
+		return list, nil
+	case map[string]interface{}:
+		// tables inside lists
+		table := make(map[string]interface{}, len(tv))
+		for key, elem := range tv {
+			v, err := ip.expand(path, elem)
+			if err != nil {
+				return nil, err
+			}
+			table[key] = v
+		}
+		return table, nil
+	default:
+		return value, nil
+	}
+}
+
+func (ip *interpolator) expandString(s string) (interface{}, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	// a single reference keeps the type of the referenced value
+	if strings.HasPrefix(s, "${") && strings.Index(s, "}") == len(s)-1 {
+		return ip.reference(s[2 : len(s)-1])
+	}
+	buf := new(strings.Builder)
+	for {
+		idx := strings.Index(s, "${")
+		if idx < 0 {
+			buf.WriteString(s)
+			return buf.String(), nil
+		}
+		if idx > 0 && s[idx-1] == '$' {
+			buf.WriteString(s[:idx-1])
+			buf.WriteString("${")
+			s = s[idx+2:]
+			continue
+		}
+		end := strings.Index(s[idx:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed reference %q", s[idx:])
+		}
+		value, err := ip.reference(s[idx+2 : idx+end])
+		if err != nil {
+			return nil, err
+		}
+		str, err := toString(value)
+		if err != nil {
+			return nil, fmt.Errorf("reference ${%s}: %w", s[idx+2:idx+end], err)
+		}
+		buf.WriteString(s[:idx])
+		buf.WriteString(str.(string))
+		s = s[idx+end+1:]
+	}
+}
+
+// reference resolves "name" or "name:-default"
+func (ip *interpolator) reference(ref string) (interface{}, error) {
+	name, def, hasDefault := ref, "", false
+	if idx := strings.Index(ref, ":-"); idx >= 0 {
+		name, def, hasDefault = ref[:idx], ref[idx+2:], true
+	}
+	if _, ok := lookupPath(ip.cs, name); ok {
+		value, err := ip.value(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := asTable(value); ok {
+			return nil, fmt.Errorf("reference ${%s} is a table", name)
+		}
+		return value, nil
+	}
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	if hasDefault {
+		return def, nil
+	}
+	return nil, fmt.Errorf("reference ${%s} is neither a key nor an OS environment", name)
+}
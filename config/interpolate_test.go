@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	err := os.Setenv("DATA_ROOT", "/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("DATA_ROOT")
+	cs, err := UnmarshalMemory([]byte(`
+[common]
+host = "10.0.0.1"
+port = 3306
+[mysql]
+host = "${common.host}"
+port = "${common.port}"
+dsn = "${mysql.host}:${mysql.port}"
+[[mysql.replicas]]
+dsn = "${common.host}:3307"
+[redis]
+addr = "${common.host}:6379"
+escaped = "$${common.host}"
+[upload]
+dirs = ["${DATA_ROOT}/avatar", "${UPLOAD_ROOT:-/tmp}/images"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Interpolate(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	need := Configs{
+		"common": map[string]interface{}{"host": "10.0.0.1", "port": int64(3306)},
+		"mysql": map[string]interface{}{
+			"host":     "10.0.0.1",
+			"port":     int64(3306),
+			"dsn":      "10.0.0.1:3306",
+			"replicas": []map[string]interface{}{{"dsn": "10.0.0.1:3307"}},
+		},
+		"redis":  map[string]interface{}{"addr": "10.0.0.1:6379", "escaped": "${common.host}"},
+		"upload": map[string]interface{}{"dirs": []interface{}{"/data/avatar", "/tmp/images"}},
+	}
+	if !reflect.DeepEqual(need, cs) {
+		t.Errorf("need: %v, got: %v\n", need, cs)
+	}
+
+	cs = Configs{"a": map[string]interface{}{"b": "x", "list": []interface{}{map[string]interface{}{"c": "${a.b}"}}}}
+	err = Interpolate(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cs.Get("a.list").([]interface{})[0].(map[string]interface{})["c"]; got != "x" {
+		t.Errorf("need: x, got: %v\n", got)
+	}
+
+	cs = Configs{"a": map[string]interface{}{"b": "${a.c}", "c": "${a.b}"}}
+	err = Interpolate(cs)
+	var ie *InterpolateError
+	if !errors.As(err, &ie) {
+		t.Errorf("need cycle error, got: %v\n", err)
+	}
+}
+
+func TestUnmarshalFile_Include(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var files = map[string]string{
+		"common.yaml": `
+common:
+  host: 10.0.0.1
+mysql:
+  host: 127.0.0.1
+  user: root
+`,
+		"app.toml": `
+include = ["common.yaml"]
+[mysql]
+host = "${common.host}"
+[redis]
+addr = "${common.port}"
+`,
+	}
+	for name, data := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	filename := filepath.Join(dir, "app.toml")
+	_, err = UnmarshalFile(filename)
+	var ie *InterpolateError
+	if !errors.As(err, &ie) || ie.File != filename || ie.Key != "redis.addr" {
+		t.Fatalf("need interpolate error of redis.addr in %s, got: %v\n", filename, err)
+	}
+
+	err = ioutil.WriteFile(filename, []byte(`
+include = ["common.yaml"]
+[mysql]
+host = "${common.host}"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := UnmarshalFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cs.GetStr("mysql.host"); got != "10.0.0.1" {
+		t.Errorf("need: 10.0.0.1, got: %s\n", got)
+	}
+	if got := cs.GetStr("mysql.user"); got != "root" {
+		t.Errorf("need: root, got: %s\n", got)
+	}
+	if cs.Has(IncludeKey) {
+		t.Errorf("include key should be removed")
+	}
+}
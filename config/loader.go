@@ -0,0 +1,345 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Sources records which layer supplied each final value, keyed by the dotted key path
+type Sources map[string]string
+
+// Of returns the layer name which supplied the value of the dotted key path
+func (s Sources) Of(path string) string {
+	return s[path]
+}
+
+// String lists every key path with its layer, sorted by key path
+func (s Sources) String() string {
+	paths := make([]string, 0, len(s))
+	for path := range s {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	buf := new(strings.Builder)
+	for _, path := range paths {
+		fmt.Fprintf(buf, "%s = %s\n", path, s[path])
+	}
+	return buf.String()
+}
+
+type layer struct {
+	name   string
+	file   string
+	source Source
+	// load returns the layer configs and the sources of the keys which are not supplied by the layer
+	// itself, such as the keys of included files, base is the result of all previous layers
+	load func(base Configs) (Configs, Sources, error)
+}
+
+// Loader builds Configs from an ordered stack of layers, later layers take precedence
+// and nested tables are merged key by key
+type Loader struct {
+	layers []layer
+
+	mu       sync.Mutex
+	includes map[string][]string // config file => files included by the last Load
+}
+
+func NewLoader() *Loader {
+	return &Loader{}
+}
+
+// Add pushes configs as a layer on top of the stack
+func (l *Loader) Add(name string, cs Configs) *Loader {
+	l.layers = append(l.layers, layer{
+		name: name,
+		load: func(Configs) (Configs, Sources, error) {
+			return cs, nil, nil
+		},
+	})
+	return l
+}
+
+// AddFile pushes a config file on top of the stack, the file is read when Load is called,
+// if optional is true, a missing file is skipped
+func (l *Loader) AddFile(filename string, optional bool) *Loader {
+	l.layers = append(l.layers, layer{
+		name: filename,
+		file: filename,
+		load: func(Configs) (Configs, Sources, error) {
+			var files []string
+			cs, sources, err := loadFile(filename, FormatOf(filename), nil, &files)
+			if len(files) > 0 {
+				// the first file is the file itself
+				l.setIncludes(filename, files[1:])
+			}
+			if err != nil && optional && os.IsNotExist(err) {
+				return nil, nil, nil
+			}
+			return cs, sources, err
+		},
+	})
+	return l
+}
+
+// AddEnvFiles pushes the base file, the optional per-environment file and the optional local
+// override file, e.g. "app.toml", "app.prod.toml" and "app.local.toml"
+func (l *Loader) AddEnvFiles(filename, env string) *Loader {
+	l.AddFile(filename, false)
+	if env != "" {
+		l.AddFile(siblingFile(filename, env), true)
+	}
+	return l.AddFile(siblingFile(filename, "local"), true)
+}
+
+// AddOSEnv pushes the OS environment values of every key already defined by previous layers,
+// values are read by OsEnvGetter
+func (l *Loader) AddOSEnv() *Loader {
+	l.layers = append(l.layers, layer{
+		name: "env",
+		load: func(base Configs) (Configs, Sources, error) {
+			cs, err := loadOSEnvLayer("", base)
+			return cs, nil, err
+		},
+	})
+	return l
+}
+
+// AddArgs pushes the command line arguments in the form of "--namespace.key=value" or
+// "--namespace.key value", values of keys defined by previous layers are parsed to the defined type
+func (l *Loader) AddArgs(args []string) *Loader {
+	l.layers = append(l.layers, layer{
+		name: "args",
+		load: func(base Configs) (Configs, Sources, error) {
+			cs, err := parseArgs(args, base)
+			return cs, nil, err
+		},
+	})
+	return l
+}
+
+func (l *Loader) setIncludes(filename string, includes []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.includes == nil {
+		l.includes = make(map[string][]string)
+	}
+	l.includes[filename] = includes
+}
+
+// Files returns the config files of the stack and the files included by them in the last Load,
+// including optional files which may not exist
+func (l *Loader) Files() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var files []string
+	seen := make(map[string]bool)
+	for _, ly := range l.layers {
+		if ly.file == "" {
+			continue
+		}
+		for _, file := range append([]string{ly.file}, l.includes[ly.file]...) {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+// Load merges all layers, resolves "${...}" references by Interpolate and returns which layer
+// supplied each final value
+func (l *Loader) Load() (Configs, Sources, error) {
+	cs := make(Configs)
+	sources := make(Sources)
+	for _, ly := range l.layers {
+		sub, subSources, err := ly.load(cs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("config: load %s: %w", ly.name, err)
+		}
+		merge(cs, sub, "", func(path string, leaf bool) {
+			for p := range sources {
+				if strings.HasPrefix(p, path+".") {
+					delete(sources, p)
+				}
+			}
+			if !leaf {
+				delete(sources, path)
+			} else if file := subSources.Of(path); file != "" {
+				sources[path] = file
+			} else {
+				sources[path] = ly.name
+			}
+		})
+	}
+	err := interpolate(cs, sources)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cs, sources, nil
+}
+
+// Merge deep merges src into dst, nested tables are merged key by key and other values of src
+// replace the values of dst
+func Merge(dst, src Configs) {
+	merge(dst, src, "", func(string, bool) {})
+}
+
+// merge deep merges src into dst, record is called with every replaced key path
+func merge(dst, src map[string]interface{}, prefix string, record func(path string, leaf bool)) {
+	for key, value := range src {
+		path := joinPath(prefix, key)
+		if st, ok := asTable(value); ok {
+			if dt, ok := asTable(dst[key]); ok {
+				merge(dt, st, path, record)
+				continue
+			}
+			dt := make(map[string]interface{}, len(st))
+			dst[key] = dt
+			record(path, false)
+			merge(dt, st, path, record)
+		} else {
+			dst[key] = value
+			record(path, true)
+		}
+	}
+}
+
+func asTable(value interface{}) (map[string]interface{}, bool) {
+	switch tv := value.(type) {
+	case map[string]interface{}:
+		return tv, true
+	case Configs:
+		return tv, true
+	default:
+		return nil, false
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// siblingFile returns "app.<name>.toml" for "app.toml"
+func siblingFile(filename, name string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "." + name + ext
+}
+
+// loadOSEnvLayer collects the OS environment values of all leaf keys of base
+func loadOSEnvLayer(namespace string, base map[string]interface{}) (Configs, error) {
+	var cs Configs
+	for key, value := range base {
+		var sub interface{}
+		if table, ok := asTable(value); ok {
+			subs, err := loadOSEnvLayer(joinPath(namespace, key), table)
+			if err != nil {
+				return nil, err
+			}
+			if len(subs) > 0 {
+				sub = map[string]interface{}(subs)
+			}
+		} else if ov := OsEnvGetter(namespace, key); ov != "" {
+			v, err := parseEnvValue(value, ov)
+			if err != nil {
+				return nil, &LoadOsError{
+					Namespace: namespace,
+					Key:       key,
+					err:       err,
+				}
+			}
+			sub = v
+		}
+		if sub != nil {
+			if cs == nil {
+				cs = make(Configs)
+			}
+			cs[key] = sub
+		}
+	}
+	return cs, nil
+}
+
+// parseArgs parses command line arguments to configs, arguments which are not in the form of
+// "--namespace.key" are ignored
+func parseArgs(args []string, base Configs) (Configs, error) {
+	cs := make(Configs)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !isFlag(arg) {
+			continue
+		}
+		arg = arg[2:]
+		var path, value string
+		if idx := strings.Index(arg, "="); idx >= 0 {
+			path, value = arg[:idx], arg[idx+1:]
+		} else if i+1 < len(args) && !isFlag(args[i+1]) {
+			path, value = arg, args[i+1]
+			i++
+		} else {
+			path, value = arg, "true"
+		}
+		if !strings.Contains(path, ".") {
+			continue
+		}
+		var v interface{} = value
+		if old, ok := lookupPath(base, path); ok {
+			pv, err := parseEnvValue(old, value)
+			if err != nil {
+				return nil, fmt.Errorf("argument --%s: %w", path, err)
+			}
+			v = pv
+		}
+		setPath(cs, path, v)
+	}
+	return cs, nil
+}
+
+// isFlag reports whether arg is in the form of "--name", so negative values such as "-1" are not flags
+func isFlag(arg string) bool {
+	if len(arg) < 3 || !strings.HasPrefix(arg, "--") {
+		return false
+	}
+	c := arg[2]
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// lookupPath returns the value of the dotted key path
+func lookupPath(cs map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		value, ok := cs[key]
+		if !ok {
+			return nil, false
+		}
+		if i == len(keys)-1 {
+			return value, true
+		}
+		if cs, ok = asTable(value); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// setPath sets the value of the dotted key path, missing tables are created
+func setPath(cs map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		sub, ok := asTable(cs[key])
+		if !ok {
+			sub = make(map[string]interface{})
+			cs[key] = sub
+		}
+		cs = sub
+	}
+	cs[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var files = map[string]string{
+		"app.toml": `
+[mysql]
+host = "127.0.0.1"
+user = "root"
+max_open_conns = 10
+[mysql.replica]
+host = "127.0.0.2"
+port = 3306
+`,
+		"app.prod.toml": `
+[mysql.replica]
+host = "10.0.0.2"
+`,
+		"app.local.toml": `
+[mysql]
+user = "dev"
+`,
+	}
+	for name, data := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = os.Setenv("mysql.replica.port", "3307")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("mysql.replica.port")
+
+	filename := filepath.Join(dir, "app.toml")
+	cs, sources, err := NewLoader().
+		AddEnvFiles(filename, "prod").
+		AddOSEnv().
+		AddArgs([]string{"--mysql.host=localhost", "--mysql.max_open_conns", "20", "-v"}).
+		Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	need := Configs{
+		"mysql": map[string]interface{}{
+			"host": "localhost",
+			"user": "dev",
+			// integers of env and args overrides keep the int64 type of TOML
+			"max_open_conns": int64(20),
+			"replica": map[string]interface{}{
+				"host": "10.0.0.2",
+				"port": int64(3307),
+			},
+		},
+	}
+	if !reflect.DeepEqual(need, cs) {
+		t.Errorf("need: %v, got: %v\n", need, cs)
+	}
+	needSources := Sources{
+		"mysql.host":           "args",
+		"mysql.user":           filepath.Join(dir, "app.local.toml"),
+		"mysql.max_open_conns": "args",
+		"mysql.replica.host":   filepath.Join(dir, "app.prod.toml"),
+		"mysql.replica.port":   "env",
+	}
+	if !reflect.DeepEqual(needSources, sources) {
+		t.Errorf("need: %v, got: %v\n", needSources, sources)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dst := Configs{"a": map[string]interface{}{"b": int64(1), "c": int64(2)}, "d": "x"}
+	Merge(dst, Configs{"a": map[string]interface{}{"c": int64(3)}, "d": map[string]interface{}{"e": true}})
+	need := Configs{"a": map[string]interface{}{"b": int64(1), "c": int64(3)}, "d": map[string]interface{}{"e": true}}
+	if !reflect.DeepEqual(need, dst) {
+		t.Errorf("need: %v, got: %v\n", need, dst)
+	}
+}
+
+func TestLoaderIncludeSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"common.toml": `
+[mysql]
+host = "127.0.0.1"
+`,
+		"app.toml": `
+include = ["common.toml"]
+[mysql]
+user = "root"
+`,
+	}
+	for name, data := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	filename := filepath.Join(dir, "app.toml")
+	_, sources, err := NewLoader().AddFile(filename, false).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	needSources := Sources{
+		"mysql.host": filepath.Join(dir, "common.toml"),
+		"mysql.user": filename,
+	}
+	if !reflect.DeepEqual(needSources, sources) {
+		t.Errorf("need: %v, got: %v\n", needSources, sources)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	base := Configs{"mysql": map[string]interface{}{"port": int64(3306), "ratio": 0.5}}
+	cs, err := parseArgs([]string{"--mysql.port", "-1", "--mysql.ratio", "-0.5", "-v", "--mysql.debug", "--mysql.host=-"}, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	need := Configs{
+		"mysql": map[string]interface{}{
+			"port":  int64(-1),
+			"ratio": -0.5,
+			"debug": "true",
+			"host":  "-",
+		},
+	}
+	if !reflect.DeepEqual(need, cs) {
+		t.Errorf("need: %v, got: %v\n", need, cs)
+	}
+}
@@ -0,0 +1,343 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Schema is a registered config struct
+type Schema struct {
+	Namespace string       // default namespace, such as "mysql"
+	Desc      string       // description, used as the comment of the TOML table
+	Type      reflect.Type // struct type
+}
+
+var schemas = struct {
+	sync.RWMutex
+	list []Schema
+}{}
+
+// RegisterSchema registers the config struct of namespace, registered schemas are used to generate
+// the sample config file, fields are documented by the `desc` and `default` tags, for example:
+//
+//	type Config struct {
+//		Host string `toml:"host" default:"127.0.0.1" desc:"连接地址"`
+//	}
+//
+//	func init() {
+//		config.RegisterSchema("mysql", "mysql 数据库配置", Config{})
+//	}
+func RegisterSchema(namespace, desc string, schema interface{}) {
+	rt := reflect.TypeOf(schema)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	schemas.Lock()
+	defer schemas.Unlock()
+	for i, s := range schemas.list {
+		if s.Namespace == namespace {
+			schemas.list[i] = Schema{Namespace: namespace, Desc: desc, Type: rt}
+			return
+		}
+	}
+	schemas.list = append(schemas.list, Schema{Namespace: namespace, Desc: desc, Type: rt})
+}
+
+// Schemas returns the registered schemas in registration order
+func Schemas() []Schema {
+	schemas.RLock()
+	defer schemas.RUnlock()
+	return append([]Schema(nil), schemas.list...)
+}
+
+// LookupSchema returns the registered schema of namespace
+func LookupSchema(namespace string) (Schema, bool) {
+	for _, s := range Schemas() {
+		if s.Namespace == namespace {
+			return s, true
+		}
+	}
+	return Schema{}, false
+}
+
+// ApplyDefaults sets the `default` tag values to the zero value fields of schema, nested structs
+// are set recursively. Unmarshal applies defaults before decoding, so missing keys keep the defaults.
+func ApplyDefaults(schema interface{}) error {
+	rv := reflect.ValueOf(schema)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("config.ApplyDefaults: need a non-nil pointer, got %T", schema)
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	return applyDefaults(rv)
+}
+
+func applyDefaults(rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		value := rv.Field(i)
+		if !value.CanSet() {
+			continue
+		}
+		if def, ok := field.Tag.Lookup("default"); ok && value.IsZero() {
+			err := setString(value, def)
+			if err != nil {
+				return fmt.Errorf("config: default value of field %s: %w", field.Name, err)
+			}
+		}
+		if value.Kind() == reflect.Struct && value.Type().PkgPath() != "time" {
+			err := applyDefaults(value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setString parses s to the kind of value, slices are separated by ","
+func setString(value reflect.Value, s string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		var elems []string
+		if s != "" {
+			elems = strings.Split(s, ",")
+		}
+		slice := reflect.MakeSlice(value.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			err := setString(slice.Index(i), strings.TrimSpace(elem))
+			if err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+	default:
+		return fmt.Errorf("not support the %s type", value.Type())
+	}
+	return nil
+}
+
+// GenerateTOML generates the commented sample TOML table of schema, values are the `default` tag
+// values or zero values, comments are the `desc` tag values
+func GenerateTOML(namespace, desc string, schema interface{}) []byte {
+	buf := new(bytes.Buffer)
+	rt := reflect.TypeOf(schema)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	writeTOMLTable(buf, namespace, desc, rt)
+	return buf.Bytes()
+}
+
+// GenerateSampleTOML generates the commented sample TOML of all registered schemas
+func GenerateSampleTOML() []byte {
+	buf := new(bytes.Buffer)
+	for i, s := range Schemas() {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		writeTOMLTable(buf, s.Namespace, s.Desc, s.Type)
+	}
+	return buf.Bytes()
+}
+
+func writeTOMLTable(buf *bytes.Buffer, namespace, desc string, rt reflect.Type) {
+	if desc != "" {
+		fmt.Fprintf(buf, "# %s\n", desc)
+	}
+	fmt.Fprintf(buf, "[%s]\n", namespace)
+	var tables []reflect.StructField
+	for _, field := range schemaFields(rt) {
+		if isTable(field.Type) {
+			tables = append(tables, field)
+			continue
+		}
+		if desc := field.Tag.Get("desc"); desc != "" {
+			fmt.Fprintf(buf, "# %s\n", desc)
+		}
+		fmt.Fprintf(buf, "%s = %s\n", fieldName(field), tomlValue(field))
+	}
+	for _, field := range tables {
+		buf.WriteString("\n")
+		writeTOMLTable(buf, namespace+"."+fieldName(field), field.Tag.Get("desc"), field.Type)
+	}
+}
+
+// schemaFields returns the fields of struct type, embedded structs are flattened
+func schemaFields(rt reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("toml") == "" {
+			fields = append(fields, schemaFields(field.Type)...)
+			continue
+		}
+		if field.PkgPath != "" || fieldName(field) == "-" {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func isTable(rt reflect.Type) bool {
+	return rt.Kind() == reflect.Struct && rt.PkgPath() != "time"
+}
+
+func tomlValue(field reflect.StructField) string {
+	value := reflect.New(field.Type).Elem()
+	if def, ok := field.Tag.Lookup("default"); ok {
+		if setString(value, def) != nil {
+			return strconv.Quote(def)
+		}
+	}
+	return formatTOML(value)
+}
+
+func formatTOML(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String:
+		return strconv.Quote(value.String())
+	case reflect.Slice, reflect.Array:
+		elems := make([]string, value.Len())
+		for i := range elems {
+			elems[i] = formatTOML(value.Index(i))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// GenerateJSONSchema generates the JSON Schema of schema, the `validate` rules required, min, max
+// and oneof are converted to the JSON Schema keywords
+func GenerateJSONSchema(schema interface{}) ([]byte, error) {
+	rt := reflect.TypeOf(schema)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	s := jsonSchemaOf(rt, "")
+	s["$schema"] = "http://json-schema.org/draft-07/schema#"
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// GenerateRegisteredJSONSchema generates the JSON Schema of all registered schemas
+func GenerateRegisteredJSONSchema() ([]byte, error) {
+	properties := make(map[string]interface{})
+	for _, s := range Schemas() {
+		properties[s.Namespace] = jsonSchemaOf(s.Type, s.Desc)
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":    "http://json-schema.org/draft-07/schema#",
+		"type":       "object",
+		"properties": properties,
+	}, "", "  ")
+}
+
+func jsonSchemaOf(rt reflect.Type, desc string) map[string]interface{} {
+	s := make(map[string]interface{})
+	if desc != "" {
+		s["description"] = desc
+	}
+	switch rt.Kind() {
+	case reflect.String:
+		s["type"] = "string"
+	case reflect.Bool:
+		s["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s["type"] = "integer"
+	case reflect.Float32, reflect.Float64:
+		s["type"] = "number"
+	case reflect.Slice, reflect.Array:
+		s["type"] = "array"
+		s["items"] = jsonSchemaOf(rt.Elem(), "")
+	case reflect.Map:
+		s["type"] = "object"
+	case reflect.Struct:
+		if !isTable(rt) {
+			s["type"] = "string"
+			break
+		}
+		s["type"] = "object"
+		properties := make(map[string]interface{})
+		var required []string
+		for _, field := range schemaFields(rt) {
+			name := fieldName(field)
+			property := jsonSchemaOf(field.Type, field.Tag.Get("desc"))
+			if def, ok := field.Tag.Lookup("default"); ok {
+				value := reflect.New(field.Type).Elem()
+				if setString(value, def) == nil {
+					property["default"] = value.Interface()
+				}
+			}
+			for _, rule := range splitRules(field.Tag.Get("validate")) {
+				ruleName, param := rule, ""
+				if idx := strings.Index(rule, "="); idx >= 0 {
+					ruleName, param = rule[:idx], rule[idx+1:]
+				}
+				switch ruleName {
+				case "required":
+					required = append(required, name)
+				case "min", "max":
+					limit, err := strconv.ParseFloat(param, 64)
+					if err != nil {
+						continue
+					}
+					switch property["type"] {
+					case "string":
+						property[ruleName+"Length"] = limit
+					case "array":
+						property[ruleName+"Items"] = limit
+					default:
+						property[map[string]string{"min": "minimum", "max": "maximum"}[ruleName]] = limit
+					}
+				case "oneof":
+					property["enum"] = strings.Fields(param)
+				case "regexp":
+					property["pattern"] = param
+				}
+			}
+			properties[name] = property
+		}
+		s["properties"] = properties
+		if len(required) > 0 {
+			s["required"] = required
+		}
+	}
+	return s
+}
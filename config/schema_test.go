@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type schemaTest struct {
+	Host    string   `toml:"host" default:"127.0.0.1" desc:"连接地址" validate:"required"`
+	Port    int      `toml:"port" default:"3306" desc:"连接端口" validate:"min=1,max=65535"`
+	Tags    []string `toml:"tags" default:"a,b"`
+	Replica struct {
+		Host string `toml:"host" default:"127.0.0.2"`
+	} `toml:"replica" desc:"从库"`
+}
+
+func TestGenerateTOML(t *testing.T) {
+	need := `# mysql 配置
+[mysql]
+# 连接地址
+host = "127.0.0.1"
+# 连接端口
+port = 3306
+tags = ["a", "b"]
+
+# 从库
+[mysql.replica]
+host = "127.0.0.2"
+`
+	data := GenerateTOML("mysql", "mysql 配置", schemaTest{})
+	if got := string(data); got != need {
+		t.Errorf("need: %s, got: %s\n", need, got)
+	}
+
+	// the generated sample decodes to the defaults
+	cs, err := UnmarshalMemory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &schemaTest{}
+	err = cs.UnmarshalSub("mysql", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Port != 3306 || s.Replica.Host != "127.0.0.2" || len(s.Tags) != 2 {
+		t.Errorf("got: %+v\n", s)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	cs, err := UnmarshalMemory([]byte(`
+[mysql]
+port = 3307
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &schemaTest{}
+	err = cs.UnmarshalSub("mysql", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Host != "127.0.0.1" || s.Port != 3307 || s.Replica.Host != "127.0.0.2" {
+		t.Errorf("got: %+v\n", s)
+	}
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	data, err := GenerateJSONSchema(schemaTest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Required   []string `json:"required"`
+		Properties map[string]struct {
+			Type    string      `json:"type"`
+			Maximum float64     `json:"maximum"`
+			Default interface{} `json:"default"`
+		} `json:"properties"`
+	}
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "host" {
+		t.Errorf("need required [host], got: %v\n", schema.Required)
+	}
+	if port := schema.Properties["port"]; port.Type != "integer" || port.Maximum != 65535 || port.Default != 3306.0 {
+		t.Errorf("got port schema: %+v\n", port)
+	}
+	if replica := schema.Properties["replica"]; replica.Type != "object" {
+		t.Errorf("got replica schema: %+v\n", replica)
+	}
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/morgine/pkg/crypt/aes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// MasterKeyEnv is the OS environment key of the master key which decrypts "enc:" values,
+// it is used if no master key is set by SetMasterKey
+const MasterKeyEnv = "CONFIG_MASTER_KEY"
+
+// Resolver dereferences a secret reference, ref is the value after the prefix,
+// such as "/run/secrets/db_pw" of "file:/run/secrets/db_pw"
+type Resolver func(ref string) (string, error)
+
+var masterKey = struct {
+	sync.RWMutex
+	key []byte
+}{}
+
+var resolvers = struct {
+	sync.RWMutex
+	m map[string]Resolver
+}{
+	m: map[string]Resolver{
+		"enc":  resolveEncrypted,
+		"file": resolveFile,
+		"env":  resolveEnv,
+	},
+}
+
+// RegisterResolver registers the resolver of string values in the form of "prefix:ref" of the fields
+// tagged `secret:"true"`, the built-in resolvers are:
+//
+//	enc:<ciphertext>   decrypts the ciphertext of aes.AesCBCEncrypt by the master key
+//	file:<filename>    reads the file, trailing newlines are trimmed
+//	env:<key>          reads the OS environment value
+func RegisterResolver(prefix string, resolver Resolver) {
+	resolvers.Lock()
+	defer resolvers.Unlock()
+	resolvers.m[prefix] = resolver
+}
+
+// SetMasterKey sets the AES key which decrypts "enc:" values
+func SetMasterKey(key []byte) {
+	masterKey.Lock()
+	defer masterKey.Unlock()
+	masterKey.key = key
+}
+
+// EncryptValue encrypts value by key, the result can be pasted into the config file
+func EncryptValue(value string, key []byte) (string, error) {
+	data, err := aes.AesCBCEncrypt([]byte(value), key)
+	if err != nil {
+		return "", err
+	}
+	return "enc:" + data, nil
+}
+
+// SecretError is returned if a secret reference cannot be resolved
+type SecretError struct {
+	Path string // dotted key path
+	err  error
+}
+
+func (e *SecretError) Error() string {
+	return fmt.Sprintf("config: resolve secret %s error: %s", e.Path, e.err)
+}
+
+func (e *SecretError) Unwrap() error {
+	return e.err
+}
+
+// ResolveSecrets returns a copy of cs in which every string value with a registered prefix
+// is replaced by the resolved value, cs is not modified. Unmarshal and UnmarshalSub only resolve
+// the fields tagged `secret:"true"`, so plain values such as the DSN "file::memory:" are kept
+func ResolveSecrets(cs Configs) (Configs, error) {
+	return resolveSecrets("", cs, nil)
+}
+
+// resolveSecrets resolves the secrets of the namespace table cs, error paths are prefixed by namespace,
+// if secrets is not nil, only the values of the key paths in secrets are resolved
+func resolveSecrets(namespace string, cs Configs, secrets map[string]bool) (Configs, error) {
+	resolved, err := resolveValue(namespace, namespace, map[string]interface{}(cs), secrets)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]interface{}), nil
+}
+
+// schemaSecrets returns the key paths of the fields of schema tagged `secret:"true"`
+func schemaSecrets(namespace string, schema interface{}) map[string]bool {
+	secrets := make(map[string]bool)
+	if rt := schemaStruct(schema); rt != nil {
+		secretPaths(namespace, rt, secrets)
+	}
+	return secrets
+}
+
+// resolveValue resolves value of path, key is the key path of the field which value belongs to,
+// it is the path of the list for list elements
+func resolveValue(path, key string, value interface{}, secrets map[string]bool) (interface{}, error) {
+	switch tv := value.(type) {
+	case string:
+		if secrets != nil && !secrets[key] {
+			return tv, nil
+		}
+		idx := strings.Index(tv, ":")
+		if idx <= 0 {
+			return tv, nil
+		}
+		resolvers.RLock()
+		resolver, ok := resolvers.m[tv[:idx]]
+		resolvers.RUnlock()
+		if !ok {
+			return tv, nil
+		}
+		v, err := resolver(tv[idx+1:])
+		if err != nil {
+			return nil, &SecretError{Path: path, err: err}
+		}
+		return v, nil
+	case []interface{}:
+		list := make([]interface{}, len(tv))
+		for i, elem := range tv {
+			v, err := resolveValue(fmt.Sprintf("%s[%d]", path, i), key, elem, secrets)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case []map[string]interface{}:
+		list := make([]map[string]interface{}, len(tv))
+		for i, elem := range tv {
+			v, err := resolveValue(fmt.Sprintf("%s[%d]", path, i), key, elem, secrets)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v.(map[string]interface{})
+		}
+		return list, nil
+	case []string:
+		list := make([]string, len(tv))
+		for i, elem := range tv {
+			v, err := resolveValue(fmt.Sprintf("%s[%d]", path, i), key, elem, secrets)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v.(string)
+		}
+		return list, nil
+	case map[string]interface{}, Configs:
+		table, _ := asTable(tv)
+		m := make(map[string]interface{}, len(table))
+		for k, sub := range table {
+			v, err := resolveValue(joinPath(path, k), joinPath(key, k), sub, secrets)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	default:
+		return tv, nil
+	}
+}
+
+func resolveEncrypted(ref string) (string, error) {
+	masterKey.RLock()
+	key := masterKey.key
+	masterKey.RUnlock()
+	if len(key) == 0 {
+		key = []byte(os.Getenv(MasterKeyEnv))
+	}
+	if len(key) == 0 {
+		return "", errors.New("master key is not set")
+	}
+	data, err := aes.AesCBCDecrypt(ref, key)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func resolveFile(ref string) (string, error) {
+	data, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func resolveEnv(ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("OS environment %s is not set", ref)
+	}
+	return value, nil
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	key := []byte("change this pass")
+	SetMasterKey(key)
+	defer SetMasterKey(nil)
+	encrypted, err := EncryptValue("enc-password", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "db_pw")
+	err = ioutil.WriteFile(filename, []byte("file-password\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Setenv("DB_PW", "env-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("DB_PW")
+
+	cs := Configs{
+		"mysql": map[string]interface{}{
+			"password": encrypted,
+			"host":     "http://localhost",
+		},
+		"postgres": map[string]interface{}{"password": "file:" + filename},
+		"redis":    map[string]interface{}{"password": "env:DB_PW"},
+	}
+	var schema struct {
+		Password string `toml:"password" secret:"true"`
+		Host     string `toml:"host"`
+	}
+	var need = map[string]string{
+		"mysql":    "enc-password",
+		"postgres": "file-password",
+		"redis":    "env-password",
+	}
+	for namespace, password := range need {
+		err = cs.UnmarshalSub(namespace, &schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if schema.Password != password {
+			t.Errorf("%s: need: %s, got: %s\n", namespace, password, schema.Password)
+		}
+	}
+	if schema.Host != "http://localhost" {
+		t.Errorf("need: http://localhost, got: %s\n", schema.Host)
+	}
+	if cs["mysql"].(map[string]interface{})["password"] != encrypted {
+		t.Error("configs should not be modified")
+	}
+
+	_, err = ResolveSecrets(Configs{"redis": map[string]interface{}{"password": "env:NOT_EXIST_PW"}})
+	if e, ok := err.(*SecretError); !ok || e.Path != "redis.password" {
+		t.Errorf("need SecretError of redis.password, got: %v\n", err)
+	}
+	var redis struct {
+		Password string `toml:"password" secret:"true"`
+	}
+	err = Configs{"redis": map[string]interface{}{"password": "env:NOT_EXIST_PW"}}.UnmarshalSub("redis", &redis)
+	if e, ok := err.(*SecretError); !ok || e.Path != "redis.password" {
+		t.Errorf("need SecretError of redis.password, got: %v\n", err)
+	}
+}
+
+func TestResolveSecretsUntagged(t *testing.T) {
+	err := os.Setenv("DB_PW", "env-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("DB_PW")
+	cs := Configs{
+		"sqlite": map[string]interface{}{
+			"dsn":      "file::memory:?cache=shared",
+			"user":     "env:DB_PW",
+			"password": "env:DB_PW",
+		},
+	}
+	var schema struct {
+		DSN      string `toml:"dsn"`
+		User     string `toml:"user"`
+		Password string `toml:"password" secret:"true"`
+	}
+	err = cs.UnmarshalSub("sqlite", &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.DSN != "file::memory:?cache=shared" {
+		t.Errorf("need: %s, got: %s\n", "file::memory:?cache=shared", schema.DSN)
+	}
+	if schema.User != "env:DB_PW" {
+		t.Errorf("need: %s, got: %s\n", "env:DB_PW", schema.User)
+	}
+	if schema.Password != "env-password" {
+		t.Errorf("need: %s, got: %s\n", "env-password", schema.Password)
+	}
+}
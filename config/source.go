@@ -0,0 +1,225 @@
+package config
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Source provides configs from a remote or key-value store, such as a redis hash or a database table
+type Source interface {
+	// Load returns the configs of the source
+	Load() (Configs, error)
+	// Watch calls onChange with the new configs whenever the source changed, it blocks until ctx is done
+	Watch(ctx context.Context, onChange func(cs Configs)) error
+}
+
+// AddSource pushes source on top of the stack, the source is loaded when Load is called and watched
+// by the Watcher of the loader, string values of keys defined by previous layers are parsed to the
+// defined type as AddOSEnv does
+func (l *Loader) AddSource(name string, source Source) *Loader {
+	l.layers = append(l.layers, layer{
+		name:   name,
+		source: source,
+		load: func(base Configs) (Configs, Sources, error) {
+			cs, err := source.Load()
+			if err != nil {
+				return nil, nil, err
+			}
+			err = coerceStrings("", base, cs)
+			if err != nil {
+				return nil, nil, err
+			}
+			return cs, nil, nil
+		},
+	})
+	return l
+}
+
+// coerceStrings parses the string values of cs to the type of the same keys of base
+func coerceStrings(prefix string, base, cs map[string]interface{}) error {
+	for key, value := range cs {
+		path := joinPath(prefix, key)
+		if table, ok := asTable(value); ok {
+			if bt, ok := asTable(base[key]); ok {
+				err := coerceStrings(path, bt, table)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		str, ok := value.(string)
+		old, defined := base[key]
+		if !ok || !defined {
+			continue
+		}
+		if _, isTable := asTable(old); isTable {
+			continue
+		}
+		v, err := parseEnvValue(old, str)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		cs[key] = v
+	}
+	return nil
+}
+
+// schemaStruct returns the struct type of schema, or nil if schema is not a struct or a pointer to struct
+func schemaStruct(schema interface{}) reflect.Type {
+	rt := reflect.TypeOf(schema)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt == nil || !isTable(rt) {
+		return nil
+	}
+	return rt
+}
+
+// coerceSchema parses the string values of table to the types of the fields of struct type rt in place,
+// so the keys which are only defined by sources, such as "3306" of an integer field, can be decoded
+func coerceSchema(prefix string, table map[string]interface{}, rt reflect.Type) error {
+	for _, field := range schemaFields(rt) {
+		key := fieldName(field)
+		value, ok := table[key]
+		if !ok {
+			continue
+		}
+		path := joinPath(prefix, key)
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case isTable(ft):
+			if sub, ok := asTable(value); ok {
+				if err := coerceSchema(path, sub, ft); err != nil {
+					return err
+				}
+			}
+		case ft.Kind() == reflect.Slice && isTable(ft.Elem()):
+			if tables, ok := asTables(value); ok {
+				for i, sub := range tables {
+					if err := coerceSchema(fmt.Sprintf("%s[%d]", path, i), sub, ft.Elem()); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			v, err := coerceValue(ft, value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			table[key] = v
+		}
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// coerceValue parses the string value to typ by parseFlag, if typ is a boolean, number or a slice of them,
+// such as "1,2" of []int, other values are returned as is
+func coerceValue(typ reflect.Type, value interface{}) (interface{}, error) {
+	if isScalar(typ) {
+		if str, ok := value.(string); ok {
+			return parseFlag(typ, str)
+		}
+	} else if typ.Kind() == reflect.Slice {
+		switch tv := value.(type) {
+		case string:
+			if isScalar(typ.Elem()) {
+				return parseFlag(typ, tv)
+			}
+		case []interface{}:
+			list := make([]interface{}, len(tv))
+			for i, elem := range tv {
+				v, err := coerceValue(typ.Elem(), elem)
+				if err != nil {
+					return nil, err
+				}
+				list[i] = v
+			}
+			return list, nil
+		case []string:
+			list := make([]interface{}, len(tv))
+			for i, elem := range tv {
+				v, err := coerceValue(typ.Elem(), elem)
+				if err != nil {
+					return nil, err
+				}
+				list[i] = v
+			}
+			return list, nil
+		}
+	}
+	return value, nil
+}
+
+// isScalar reports whether typ is a boolean or number type without a custom text decoding
+func isScalar(typ reflect.Type) bool {
+	if reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// sources returns the sources of the stack
+func (l *Loader) sources() []Source {
+	var sources []Source
+	for _, ly := range l.layers {
+		if ly.source != nil {
+			sources = append(sources, ly.source)
+		}
+	}
+	return sources
+}
+
+// FromKeyValues builds Configs from flat dotted keys, such as "mysql.host", values are kept as raw
+// strings, AddSource parses them to the types defined by previous layers, so "123456" stays a string
+// for a string field and "3306" becomes an integer for an integer field
+func FromKeyValues(kvs map[string]string) Configs {
+	cs := make(Configs)
+	for key, value := range kvs {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		setPath(cs, key, value)
+	}
+	return cs
+}
+
+// PollSource calls load every interval and calls onChange if the configs changed, load errors are
+// skipped and the previous configs are kept, it blocks until ctx is done
+func PollSource(ctx context.Context, interval time.Duration, load func() (Configs, error), onChange func(cs Configs)) error {
+	last, _ := load()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cs, err := load()
+			if err != nil {
+				continue
+			}
+			if !reflect.DeepEqual(last, cs) {
+				last = cs
+				onChange(cs)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
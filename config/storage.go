@@ -1,24 +1,257 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
 )
 
+// Format is the encoding of config data
+type Format string
+
+const (
+	TOML Format = "toml"
+	YAML Format = "yaml"
+	JSON Format = "json"
+)
+
+// FormatOf detects the config format by the file extension, unknown extensions are treated as TOML
+func FormatOf(filename string) Format {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return YAML
+	case ".json":
+		return JSON
+	default:
+		return TOML
+	}
+}
+
+// UnmarshalMemory decodes TOML data
 func UnmarshalMemory(data []byte) (Configs, error) {
+	return UnmarshalFormat(data, TOML)
+}
+
+// UnmarshalFormat decodes data with the given format, values are normalized to the types
+// produced by the TOML decoder, so the same Configs tree is built for every format
+func UnmarshalFormat(data []byte, format Format) (Configs, error) {
 	cs := make(Configs)
-	err := toml.Unmarshal(data, &cs)
-	if err != nil {
-		return nil, err
-	} else {
+	switch format {
+	case TOML, "":
+		err := toml.Unmarshal(data, &cs)
+		if err != nil {
+			return nil, err
+		} else {
+			return cs, nil
+		}
+	case YAML:
+		var raw map[interface{}]interface{}
+		err := yaml.Unmarshal(data, &raw)
+		if err != nil {
+			return nil, err
+		}
+		value, err := normalize("", map[interface{}]interface{}(raw))
+		if err != nil {
+			return nil, err
+		}
+		for key, sub := range value.(map[string]interface{}) {
+			cs[key] = sub
+		}
 		return cs, nil
+	case JSON:
+		var raw map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err := decoder.Decode(&raw)
+		if err != nil {
+			return nil, err
+		}
+		value, err := normalize("", raw)
+		if err != nil {
+			return nil, err
+		}
+		for key, sub := range value.(map[string]interface{}) {
+			cs[key] = sub
+		}
+		return cs, nil
+	default:
+		return nil, fmt.Errorf("config: unsupported format %q", format)
 	}
 }
 
+// IncludeKey is the top-level key which lists the files to include, such as:
+//
+//	include = ["common.toml"]
+//
+// paths are relative to the including file, keys of the including file override the included keys
+const IncludeKey = "include"
+
+// UnmarshalFile decodes file, the format is detected by the file extension, included files are
+// merged and "${...}" references are resolved by Interpolate
 func UnmarshalFile(filename string) (Configs, error) {
-	data, err := ioutil.ReadFile(filename)
+	return UnmarshalFileFormat(filename, FormatOf(filename))
+}
+
+// UnmarshalFileFormat decodes file with the given format, included files are detected by their
+// own extensions
+func UnmarshalFileFormat(filename string, format Format) (Configs, error) {
+	cs, sources, err := loadFile(filename, format, nil, nil)
 	if err != nil {
 		return nil, err
 	}
-	return UnmarshalMemory(data)
+	err = interpolate(cs, sources)
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// loadFile decodes file and merges the included files without interpolation, stack is the
+// including files for cycle detection, if files is not nil, every file which is read or failed to
+// read is appended to it
+func loadFile(filename string, format Format, stack []string, files *[]string) (Configs, Sources, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, including := range stack {
+		if including == abs {
+			return nil, nil, fmt.Errorf("config: include cycle %s -> %s", strings.Join(stack, " -> "), abs)
+		}
+	}
+	if files != nil {
+		*files = append(*files, filename)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	cs, err := UnmarshalFormat(data, format)
+	if err != nil {
+		return nil, nil, fmt.Errorf("config: decode %s error: %w", filename, err)
+	}
+	includes, err := includeFiles(cs[IncludeKey])
+	if err != nil {
+		return nil, nil, fmt.Errorf("config: %s of %s: %w", IncludeKey, filename, err)
+	}
+	delete(cs, IncludeKey)
+	result, sources := make(Configs), make(Sources)
+	record := func(file string) func(path string, leaf bool) {
+		return func(path string, leaf bool) {
+			for p := range sources {
+				if strings.HasPrefix(p, path+".") {
+					delete(sources, p)
+				}
+			}
+			if leaf {
+				sources[path] = file
+			} else {
+				delete(sources, path)
+			}
+		}
+	}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(filename), include)
+		}
+		sub, subSources, err := loadFile(include, FormatOf(include), append(stack, abs), files)
+		if err != nil {
+			return nil, nil, fmt.Errorf("config: include %s of %s: %w", include, filename, err)
+		}
+		merge(result, sub, "", func(path string, leaf bool) {
+			record(subSources[path])(path, leaf)
+		})
+	}
+	merge(result, cs, "", record(filename))
+	return result, sources, nil
+}
+
+func includeFiles(value interface{}) ([]string, error) {
+	switch tv := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{tv}, nil
+	case []interface{}:
+		files := make([]string, len(tv))
+		for i, file := range tv {
+			s, ok := file.(string)
+			if !ok {
+				return nil, fmt.Errorf("need a list of file names, got %T", file)
+			}
+			files[i] = s
+		}
+		return files, nil
+	default:
+		return nil, fmt.Errorf("need a list of file names, got %T", value)
+	}
+}
+
+// normalize converts YAML and JSON values to TOML decoded types: tables become map[string]interface{},
+// integers become int64 and null values are dropped, integers which overflow int64 are errors
+func normalize(path string, value interface{}) (interface{}, error) {
+	switch tv := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(tv))
+		for key, sub := range tv {
+			sub, err := normalize(joinPath(path, fmt.Sprint(key)), sub)
+			if err != nil {
+				return nil, err
+			}
+			if sub != nil {
+				m[fmt.Sprint(key)] = sub
+			}
+		}
+		return m, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(tv))
+		for key, sub := range tv {
+			sub, err := normalize(joinPath(path, key), sub)
+			if err != nil {
+				return nil, err
+			}
+			if sub != nil {
+				m[key] = sub
+			}
+		}
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, 0, len(tv))
+		for i, sub := range tv {
+			sub, err := normalize(fmt.Sprintf("%s[%d]", path, i), sub)
+			if err != nil {
+				return nil, err
+			}
+			if sub != nil {
+				s = append(s, sub)
+			}
+		}
+		return s, nil
+	case json.Number:
+		if i, err := tv.Int64(); err == nil {
+			return i, nil
+		}
+		if !strings.ContainsAny(tv.String(), ".eE") {
+			return nil, fmt.Errorf("config: %s: integer %s overflows int64", path, tv)
+		}
+		f, _ := tv.Float64()
+		return f, nil
+	case int:
+		return int64(tv), nil
+	case uint64:
+		if tv > math.MaxInt64 {
+			return nil, fmt.Errorf("config: %s: integer %d overflows int64", path, tv)
+		}
+		return int64(tv), nil
+	case float32:
+		return float64(tv), nil
+	default:
+		return tv, nil
+	}
 }
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalFormat(t *testing.T) {
+	var data = map[Format]string{
+		TOML: `
+[mysql]
+host = "127.0.0.1"
+port = 3306
+tags = ["a", "b"]
+[mysql.replica]
+host = "127.0.0.2"
+`,
+		YAML: `
+mysql:
+  host: 127.0.0.1
+  port: 3306
+  tags: [a, b]
+  replica:
+    host: 127.0.0.2
+`,
+		JSON: `{"mysql": {"host": "127.0.0.1", "port": 3306, "tags": ["a", "b"], "replica": {"host": "127.0.0.2"}}}`,
+	}
+	need, err := UnmarshalFormat([]byte(data[TOML]), TOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []Format{YAML, JSON} {
+		got, err := UnmarshalFormat([]byte(data[format]), format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(need, got) {
+			t.Errorf("%s: need: %#v, got: %#v\n", format, need, got)
+		}
+	}
+}
+
+func TestUnmarshalFormatOverflow(t *testing.T) {
+	_, err := UnmarshalFormat([]byte("mysql:\n  max_open_conns: 18446744073709551615\n"), YAML)
+	if err == nil || !strings.Contains(err.Error(), "mysql.max_open_conns") {
+		t.Errorf("need overflow error of mysql.max_open_conns, got: %v\n", err)
+	}
+	cs, err := UnmarshalFormat([]byte("mysql:\n  max_open_conns: 9223372036854775807\n"), YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cs.Get("mysql.max_open_conns"); got != int64(9223372036854775807) {
+		t.Errorf("need: 9223372036854775807, got: %v\n", got)
+	}
+	_, err = UnmarshalFormat([]byte(`{"mysql": {"max_open_conns": 18446744073709551615}}`), JSON)
+	if err == nil || !strings.Contains(err.Error(), "mysql.max_open_conns") {
+		t.Errorf("need overflow error of mysql.max_open_conns, got: %v\n", err)
+	}
+	cs, err = UnmarshalFormat([]byte(`{"mysql": {"ratio": 1e20}}`), JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cs.Get("mysql.ratio"); got != 1e20 {
+		t.Errorf("need: 1e20, got: %v\n", got)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	var files = map[string]Format{
+		"app.toml":     TOML,
+		"app.yaml":     YAML,
+		"app.prod.yml": YAML,
+		"app.JSON":     JSON,
+		"app":          TOML,
+	}
+	for file, need := range files {
+		if got := FormatOf(file); got != need {
+			t.Errorf("%s: need: %s, got: %s\n", file, need, got)
+		}
+	}
+}
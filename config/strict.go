@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnknownKey is a key which is not decoded into any schema
+type UnknownKey struct {
+	Path string // dotted key path
+	File string // file which supplied the key, empty if unknown
+	Line int    // line number in file, 0 if unknown
+}
+
+func (k UnknownKey) String() string {
+	switch {
+	case k.File != "" && k.Line > 0:
+		return fmt.Sprintf("%s (%s:%d)", k.Path, k.File, k.Line)
+	case k.File != "":
+		return fmt.Sprintf("%s (%s)", k.Path, k.File)
+	default:
+		return k.Path
+	}
+}
+
+// StrictError lists the unknown keys or unused namespaces
+type StrictError struct {
+	Reason string
+	Keys   []UnknownKey
+}
+
+func (e *StrictError) Error() string {
+	keys := make([]string, len(e.Keys))
+	for i, key := range e.Keys {
+		keys[i] = key.String()
+	}
+	return fmt.Sprintf("config: %s: %s", e.Reason, strings.Join(keys, ", "))
+}
+
+// UnmarshalStrict is the same as Unmarshal but returns a *StrictError if cs contains unknown keys
+func (cs Configs) UnmarshalStrict(schema interface{}) error {
+	return cs.decode("", schema, true, nil)
+}
+
+// UnmarshalSubStrict is the same as UnmarshalSub but returns a *StrictError if the namespace contains
+// unknown keys
+func (cs Configs) UnmarshalSubStrict(namespace string, schema interface{}) error {
+	envs, err := cs.GetSub(namespace)
+	if err != nil {
+		return err
+	}
+	return envs.decode(namespace, schema, true, nil)
+}
+
+// StrictDecoder decodes namespaces of configs in strict mode, unknown keys are reported with the file
+// and line which supplied them, and the namespaces which are never decoded are reported by Check,
+// for example:
+//
+//	configs, sources, err := config.NewLoader().AddFile("app.toml", false).Load()
+//	decoder := config.NewStrictDecoder(configs, sources)
+//	err = decoder.UnmarshalSub("mysql", &mysqlConfig)
+//	// decode other namespaces ...
+//	err = decoder.Check()
+//
+// app.BootstrapStrict decodes the namespaces of the registered components by the decoder and checks it.
+type StrictDecoder struct {
+	cs      Configs
+	sources Sources
+	mu      sync.Mutex
+	used    map[string]bool
+}
+
+// NewStrictDecoder returns the strict decoder of cs, sources may be nil
+func NewStrictDecoder(cs Configs, sources Sources) *StrictDecoder {
+	return &StrictDecoder{cs: cs, sources: sources, used: make(map[string]bool)}
+}
+
+// Configs returns the configs of the decoder
+func (d *StrictDecoder) Configs() Configs {
+	return d.cs
+}
+
+// Unmarshal decodes all configs to schema and marks every namespace as used
+func (d *StrictDecoder) Unmarshal(schema interface{}) error {
+	d.mu.Lock()
+	for namespace := range d.cs {
+		d.used[namespace] = true
+	}
+	d.mu.Unlock()
+	return d.cs.decode("", schema, true, d.sources)
+}
+
+// UnmarshalSub decodes namespace to schema and marks namespace as used
+func (d *StrictDecoder) UnmarshalSub(namespace string, schema interface{}) error {
+	envs, err := d.cs.GetSub(namespace)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.used[namespace] = true
+	d.mu.Unlock()
+	return envs.decode(namespace, schema, true, d.sources)
+}
+
+// Unused returns the namespaces which are never decoded, sorted by path
+func (d *StrictDecoder) Unused() []UnknownKey {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var keys []UnknownKey
+	for namespace, value := range d.cs {
+		if _, ok := asTable(value); ok && !d.used[namespace] {
+			keys = append(keys, locate(namespace, d.sources))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Path < keys[j].Path
+	})
+	return keys
+}
+
+// Check returns a *StrictError if any namespace is never decoded
+func (d *StrictDecoder) Check() error {
+	if keys := d.Unused(); len(keys) > 0 {
+		return &StrictError{Reason: "unused namespaces", Keys: keys}
+	}
+	return nil
+}
+
+// unknownKeys builds the unknown keys of undecoded key paths relative to namespace
+func unknownKeys(namespace string, undecoded []string, sources Sources) []UnknownKey {
+	keys := make([]UnknownKey, len(undecoded))
+	for i, path := range undecoded {
+		keys[i] = locate(joinPath(namespace, path), sources)
+	}
+	return keys
+}
+
+// locate finds the file and line of the key path
+func locate(path string, sources Sources) UnknownKey {
+	key := UnknownKey{Path: path, File: sources.Of(path)}
+	if key.File == "" {
+		// tables are not recorded, use the file of any key in the table
+		for p, file := range sources {
+			if strings.HasPrefix(p, path+".") {
+				key.File = file
+				break
+			}
+		}
+	}
+	if key.File != "" {
+		key.Line = findLine(key.File, path)
+	}
+	return key
+}
+
+var (
+	tomlTable = regexp.MustCompile(`^\s*\[+\s*([^\]]+?)\s*\]+`)
+	tomlKey   = regexp.MustCompile(`^\s*([A-Za-z0-9_\-."']+?)\s*=`)
+)
+
+// findLine returns the line number of the key path in file, or 0 if not found
+func findLine(file, path string) int {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	isTOML := FormatOf(file) == TOML
+	segments := strings.Split(path, ".")
+	var table string
+	next := 0
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if isTOML {
+			if m := tomlTable.FindStringSubmatch(text); m != nil {
+				table = unquoteKey(m[1])
+				if table == path {
+					return line
+				}
+			} else if m := tomlKey.FindStringSubmatch(text); m != nil {
+				if joinPath(table, unquoteKey(m[1])) == path {
+					return line
+				}
+			}
+			continue
+		}
+		// YAML and JSON, find the segments in order
+		trimmed := strings.TrimLeft(text, " \t{,")
+		segment := segments[next]
+		if strings.HasPrefix(trimmed, segment+":") || strings.HasPrefix(trimmed, `"`+segment+`"`) {
+			next++
+			if next == len(segments) {
+				return line
+			}
+		}
+	}
+	return 0
+}
+
+func unquoteKey(key string) string {
+	return strings.NewReplacer(`"`, "", "'", "", " ", "").Replace(key)
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.toml")
+	err = ioutil.WriteFile(filename, []byte(`[mysql]
+host = "127.0.0.1"
+max_open_con = 10
+
+[mysql.replica]
+host = "127.0.0.2"
+
+[redis]
+addr = "localhost:6379"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, sources, err := NewLoader().AddFile(filename, false).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := NewStrictDecoder(cs, sources)
+
+	var mysql struct {
+		Host         string `toml:"host"`
+		MaxOpenConns int    `toml:"max_open_conns"`
+	}
+	err = cs.UnmarshalSub("mysql", &mysql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = decoder.UnmarshalSub("mysql", &mysql)
+	var se *StrictError
+	if !errors.As(err, &se) {
+		t.Fatalf("need StrictError, got: %v\n", err)
+	}
+	need := []UnknownKey{
+		{Path: "mysql.max_open_con", File: filename, Line: 3},
+		{Path: "mysql.replica.host", File: filename, Line: 6},
+	}
+	if !reflect.DeepEqual(need, se.Keys) {
+		t.Errorf("need: %v, got: %v\n", need, se.Keys)
+	}
+
+	// without sources only the key paths are known
+	err = cs.UnmarshalSubStrict("mysql", &mysql)
+	if !errors.As(err, &se) || len(se.Keys) != 2 || se.Keys[0] != (UnknownKey{Path: "mysql.max_open_con"}) {
+		t.Errorf("need: mysql.max_open_con, got: %v\n", err)
+	}
+
+	err = decoder.Check()
+	if !errors.As(err, &se) {
+		t.Fatalf("need StrictError, got: %v\n", err)
+	}
+	need = []UnknownKey{{Path: "redis", File: filename, Line: 8}}
+	if !reflect.DeepEqual(need, se.Keys) {
+		t.Errorf("need: %v, got: %v\n", need, se.Keys)
+	}
+}
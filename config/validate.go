@@ -0,0 +1,295 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Validator validates the field value, param is the text after "=" in the rule, such as "1" of "min=1"
+type Validator func(value reflect.Value, param string) error
+
+var validators = struct {
+	sync.RWMutex
+	m map[string]Validator
+}{
+	m: map[string]Validator{
+		"required": validateRequired,
+		"min":      validateMin,
+		"max":      validateMax,
+		"len":      validateLen,
+		"oneof":    validateOneOf,
+		"regexp":   validateRegexp,
+		"url":      validateURL,
+		"hostport": validateHostPort,
+	},
+}
+
+// RegisterValidator registers a custom validator which can be used in the `validate` tag by name,
+// the built-in validators are required, min, max, len, oneof, regexp, url and hostport
+func RegisterValidator(name string, validator Validator) {
+	validators.Lock()
+	defer validators.Unlock()
+	validators.m[name] = validator
+}
+
+// FieldError is the validation error of a single field
+type FieldError struct {
+	Path string // dotted key path, such as "mysql.port"
+	Rule string // failed rule, such as "max=65535"
+	err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Path, e.err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.err
+}
+
+// ValidationErrors lists every invalid field
+type ValidationErrors []*FieldError
+
+func (es ValidationErrors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("config: %d invalid field(s): %s", len(es), strings.Join(msgs, "; "))
+}
+
+// Validate validates the struct fields by `validate` tags, for example:
+//
+//	type Config struct {
+//	    Host string `toml:"host" validate:"required"`
+//	    Port int    `toml:"port" validate:"min=1,max=65535"`
+//	}
+//
+// rules are separated by ",", the regexp rule must be the last rule because the pattern may contain ",".
+// Nested structs are validated recursively, field paths are built from the toml tags.
+// If any field is invalid, a ValidationErrors is returned.
+func Validate(schema interface{}) error {
+	return ValidateNamespace("", schema)
+}
+
+// ValidateNamespace is the same as Validate but field paths are prefixed by namespace
+func ValidateNamespace(namespace string, schema interface{}) error {
+	var es ValidationErrors
+	rv := reflect.ValueOf(schema)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		validateStruct(namespace, rv, &es)
+	}
+	if len(es) > 0 {
+		return es
+	}
+	return nil
+}
+
+func validateStruct(prefix string, rv reflect.Value, es *ValidationErrors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		value := rv.Field(i)
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		path := joinPath(prefix, name)
+		if field.Anonymous && field.Tag.Get("toml") == "" {
+			// embedded structs are flattened by toml
+			path = prefix
+		}
+		if tag := field.Tag.Get("validate"); tag != "" {
+			for _, rule := range splitRules(tag) {
+				name, param := rule, ""
+				if idx := strings.Index(rule, "="); idx >= 0 {
+					name, param = rule[:idx], rule[idx+1:]
+				}
+				validators.RLock()
+				validator, ok := validators.m[name]
+				validators.RUnlock()
+				var err error
+				if !ok {
+					err = fmt.Errorf("has unknown validation rule %q", name)
+				} else {
+					err = validator(value, param)
+				}
+				if err != nil {
+					*es = append(*es, &FieldError{Path: path, Rule: rule, err: err})
+					break
+				}
+			}
+		}
+		// only nested struct values are validated, pointers may be cyclic
+		if value.Kind() == reflect.Struct && value.Type().PkgPath() != "time" {
+			validateStruct(path, value, es)
+		}
+	}
+}
+
+// fieldName returns the toml key of the struct field
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("toml"), ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+	return name
+}
+
+func splitRules(tag string) []string {
+	var rules []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "regexp=") {
+			return append(rules, tag)
+		}
+		idx := strings.Index(tag, ",")
+		if idx < 0 {
+			return append(rules, tag)
+		}
+		rules = append(rules, tag[:idx])
+		tag = tag[idx+1:]
+	}
+	return rules
+}
+
+func validateRequired(value reflect.Value, _ string) error {
+	if value.IsZero() {
+		return errors.New("is required")
+	}
+	if (value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.Len() == 0 {
+		return errors.New("is required")
+	}
+	return nil
+}
+
+func validateMin(value reflect.Value, param string) error {
+	n, isLen, err := number(value, param)
+	if err != nil {
+		return err
+	}
+	limit, _ := strconv.ParseFloat(param, 64)
+	if n < limit {
+		if isLen {
+			return fmt.Errorf("length must be at least %s", param)
+		}
+		return fmt.Errorf("must be at least %s", param)
+	}
+	return nil
+}
+
+func validateMax(value reflect.Value, param string) error {
+	n, isLen, err := number(value, param)
+	if err != nil {
+		return err
+	}
+	limit, _ := strconv.ParseFloat(param, 64)
+	if n > limit {
+		if isLen {
+			return fmt.Errorf("length must be at most %s", param)
+		}
+		return fmt.Errorf("must be at most %s", param)
+	}
+	return nil
+}
+
+func validateLen(value reflect.Value, param string) error {
+	n, isLen, err := number(value, param)
+	if err != nil {
+		return err
+	}
+	limit, _ := strconv.ParseFloat(param, 64)
+	if !isLen {
+		return fmt.Errorf("len rule is not supported by %s", value.Kind())
+	}
+	if n != limit {
+		return fmt.Errorf("length must be %s", param)
+	}
+	return nil
+}
+
+// number returns the number value, or the length of strings, slices and maps
+func number(value reflect.Value, param string) (n float64, isLen bool, err error) {
+	if _, err = strconv.ParseFloat(param, 64); err != nil {
+		return 0, false, fmt.Errorf("has invalid rule parameter %q", param)
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), false, nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), false, nil
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), true, nil
+	default:
+		return 0, false, fmt.Errorf("is not a number or length")
+	}
+}
+
+func validateOneOf(value reflect.Value, param string) error {
+	got := fmt.Sprint(value)
+	for _, option := range strings.Fields(param) {
+		if got == option {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of [%s], got %q", param, got)
+}
+
+func validateRegexp(value reflect.Value, param string) error {
+	reg, err := regexp.Compile(param)
+	if err != nil {
+		return fmt.Errorf("has invalid regexp %q: %w", param, err)
+	}
+	if !reg.MatchString(fmt.Sprint(value)) {
+		return fmt.Errorf("must match %s", param)
+	}
+	return nil
+}
+
+func validateURL(value reflect.Value, _ string) error {
+	if value.Kind() != reflect.String {
+		return errors.New("is not a string")
+	}
+	if value.Len() == 0 {
+		return nil
+	}
+	u, err := url.Parse(value.String())
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("must be an absolute URL, got %q", value.String())
+	}
+	return nil
+}
+
+func validateHostPort(value reflect.Value, _ string) error {
+	if value.Kind() != reflect.String {
+		return errors.New("is not a string")
+	}
+	if value.Len() == 0 {
+		return nil
+	}
+	_, port, err := net.SplitHostPort(value.String())
+	if err != nil {
+		return fmt.Errorf("must be host:port, got %q", value.String())
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 0 || p > 65535 {
+		return fmt.Errorf("has invalid port %q", port)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	RegisterValidator("even", func(value reflect.Value, _ string) error {
+		if value.Int()%2 != 0 {
+			return errors.New("must be even")
+		}
+		return nil
+	})
+	type base struct {
+		MaxConns int `toml:"max_conns" validate:"min=0"`
+	}
+	type schema struct {
+		Host    string `toml:"host" validate:"required"`
+		Port    int    `toml:"port" validate:"min=1,max=65535"`
+		Mode    string `toml:"mode" validate:"oneof=disable require"`
+		Name    string `toml:"name" validate:"regexp=^[a-z]{1,3}$"`
+		URL     string `toml:"url" validate:"url"`
+		Addr    string `toml:"addr" validate:"hostport"`
+		Shards  int    `toml:"shards" validate:"even"`
+		Replica struct {
+			Host string `toml:"host" validate:"required"`
+		} `toml:"replica"`
+		base
+	}
+	s := &schema{
+		Port:   70000,
+		Mode:   "prefer",
+		Name:   "abcd",
+		URL:    "localhost",
+		Addr:   "localhost",
+		Shards: 3,
+		base:   base{MaxConns: -1},
+	}
+	err := ValidateNamespace("mysql", s)
+	es, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("need ValidationErrors, got: %v", err)
+	}
+	var paths []string
+	for _, e := range es {
+		paths = append(paths, e.Path)
+	}
+	need := []string{"mysql.host", "mysql.port", "mysql.mode", "mysql.name", "mysql.url", "mysql.addr", "mysql.shards", "mysql.replica.host", "mysql.max_conns"}
+	if !reflect.DeepEqual(need, paths) {
+		t.Errorf("need: %v, got: %v\n", need, paths)
+	}
+
+	s = &schema{Host: "127.0.0.1", Port: 3306, Mode: "disable", Name: "abc", URL: "http://localhost", Addr: "localhost:6379", Shards: 2}
+	s.Replica.Host = "127.0.0.2"
+	if err = Validate(s); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Watcher polls config files and the files included by them, watches the sources of the loader and
+// reloads the configs when any of them changed, a valid reload atomically replaces the current configs
+// and notifies the subscribers of changed namespaces, an invalid reload is rejected and the previous
+// configs are kept
+type Watcher struct {
+	load     func() (Configs, error)
+	files    func() []string
+	sources  []Source
+	interval time.Duration
+
+	// reloadMu serializes reloads, so an older load never replaces a newer one
+	reloadMu  sync.Mutex
+	mu        sync.RWMutex
+	configs   Configs
+	stamps    map[string]fileStamp
+	validator func(Configs) error
+	onError   func(error)
+	subs      map[string][]func(old, new Configs)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	start  sync.Once
+	wg     sync.WaitGroup
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewFileWatcher loads filename and returns a watcher which reloads it when it or any included file changed
+func NewFileWatcher(filename string, interval time.Duration) (*Watcher, error) {
+	return NewLoaderWatcher(NewLoader().AddFile(filename, false), interval)
+}
+
+// NewLoaderWatcher loads loader and returns a watcher which reloads it when any file or source of
+// loader changed
+func NewLoaderWatcher(loader *Loader, interval time.Duration) (*Watcher, error) {
+	w, err := newWatcher(func() (Configs, error) {
+		cs, _, err := loader.Load()
+		return cs, err
+	}, loader.Files, interval)
+	if err != nil {
+		return nil, err
+	}
+	w.sources = loader.sources()
+	return w, nil
+}
+
+// newWatcher loads the configs by load, files returns the files to poll, which may change after
+// every load
+func newWatcher(load func() (Configs, error), files func() []string, interval time.Duration) (*Watcher, error) {
+	w := &Watcher{
+		load:     load,
+		files:    files,
+		interval: interval,
+		subs:     make(map[string][]func(old, new Configs)),
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	cs, err := load()
+	if err != nil {
+		return nil, err
+	}
+	w.stamps = w.stat()
+	w.configs = cs
+	return w, nil
+}
+
+// Configs returns the current configs
+func (w *Watcher) Configs() Configs {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.configs
+}
+
+// SetValidator sets the validator of reloaded configs, configs which fail validation are rejected
+func (w *Watcher) SetValidator(validator func(cs Configs) error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.validator = validator
+}
+
+// OnError sets the handler of reload errors, errors are ignored by default
+func (w *Watcher) OnError(handler func(err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = handler
+}
+
+// Watch subscribes the changes of namespace, old or new is nil if the namespace does not exist,
+// fn is called in the order of reloads and must not call Reload or Close
+func (w *Watcher) Watch(namespace string, fn func(old, new Configs)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs[namespace] = append(w.subs[namespace], fn)
+}
+
+// Start polls the files every interval and watches the sources until Close is called, calls after
+// the first one have no effect
+func (w *Watcher) Start() {
+	w.start.Do(w.run)
+}
+
+func (w *Watcher) run() {
+	for _, source := range w.sources {
+		w.wg.Add(1)
+		go func(source Source) {
+			defer w.wg.Done()
+			err := source.Watch(w.ctx, func(Configs) {
+				w.reload()
+			})
+			if err != nil {
+				w.handleError(err)
+			}
+		}(source)
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				stamps := w.stat()
+				w.mu.RLock()
+				changed := !reflect.DeepEqual(stamps, w.stamps)
+				w.mu.RUnlock()
+				if changed {
+					w.reload()
+				}
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Close stops polling and watching, and waits for the running reload to finish
+func (w *Watcher) Close() error {
+	w.cancel()
+	w.wg.Wait()
+	return nil
+}
+
+func (w *Watcher) reload() {
+	err := w.Reload()
+	if err != nil {
+		w.handleError(err)
+	}
+}
+
+func (w *Watcher) handleError(err error) {
+	w.mu.RLock()
+	onError := w.onError
+	w.mu.RUnlock()
+	if onError != nil {
+		onError(err)
+	}
+}
+
+// Reload loads and validates the configs, replaces the current configs and notifies the subscribers
+// of changed namespaces, if loading or validation failed, the current configs are kept
+func (w *Watcher) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	stamps := w.stat()
+	cs, err := w.load()
+	if !sameFiles(stamps, w.files()) {
+		// the included files are changed, they are stamped after loading
+		stamps = w.stat()
+	}
+	if err == nil {
+		w.mu.RLock()
+		validator := w.validator
+		w.mu.RUnlock()
+		if validator != nil {
+			err = validator(cs)
+		}
+	}
+	w.mu.Lock()
+	// the files are not reloaded again until they are changed again
+	w.stamps = stamps
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	old := w.configs
+	w.configs = cs
+	type notify struct {
+		fn       func(old, new Configs)
+		old, new Configs
+	}
+	var notifies []notify
+	for namespace, fns := range w.subs {
+		oldSub, newSub := subConfigs(old, namespace), subConfigs(cs, namespace)
+		if !reflect.DeepEqual(oldSub, newSub) {
+			for _, fn := range fns {
+				notifies = append(notifies, notify{fn: fn, old: oldSub, new: newSub})
+			}
+		}
+	}
+	w.mu.Unlock()
+	for _, n := range notifies {
+		n.fn(n.old, n.new)
+	}
+	return nil
+}
+
+// stat returns the stamps of the files, the stamps of missing files are zero
+func (w *Watcher) stat() map[string]fileStamp {
+	files := w.files()
+	stamps := make(map[string]fileStamp, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err == nil {
+			stamps[file] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		} else {
+			stamps[file] = fileStamp{}
+		}
+	}
+	return stamps
+}
+
+// sameFiles reports whether stamps are the stamps of files
+func sameFiles(stamps map[string]fileStamp, files []string) bool {
+	for _, file := range files {
+		if _, ok := stamps[file]; !ok {
+			return false
+		}
+	}
+	return len(stamps) == len(files)
+}
+
+// subConfigs returns the table of namespace without loading OS environment values
+func subConfigs(cs Configs, namespace string) Configs {
+	if table, ok := asTable(cs[namespace]); ok {
+		return table
+	}
+	return nil
+}
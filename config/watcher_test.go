@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.toml")
+	write := func(data string) {
+		err := ioutil.WriteFile(filename, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`
+[redis]
+addr = "localhost:6379"
+[mysql]
+host = "127.0.0.1"
+`)
+	w, err := NewFileWatcher(filename, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.SetValidator(func(cs Configs) error {
+		if _, ok := cs["redis"]; !ok {
+			return errors.New("require redis")
+		}
+		return nil
+	})
+	changes := make(chan Configs, 1)
+	w.Watch("redis", func(old, new Configs) {
+		changes <- new
+	})
+	w.Watch("mysql", func(old, new Configs) {
+		t.Errorf("mysql is not changed")
+	})
+	w.Start()
+
+	write(`
+[redis]
+addr = "localhost:6380"
+[mysql]
+host = "127.0.0.1"
+`)
+	select {
+	case cs := <-changes:
+		if got := cs["addr"]; got != "localhost:6380" {
+			t.Errorf("need: localhost:6380, got: %v\n", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload timeout")
+	}
+
+	write(`
+[mysql]
+host = "127.0.0.1"
+`)
+	if err = w.Reload(); err == nil {
+		t.Fatal("need validation error")
+	}
+	if got := subConfigs(w.Configs(), "redis")["addr"]; got != "localhost:6380" {
+		t.Errorf("need previous configs, got: %v\n", got)
+	}
+}
+
+func TestWatcherIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, data string) {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("common.toml", `
+[redis]
+addr = "localhost:6379"
+`)
+	write("app.toml", `
+include = ["common.toml"]
+[mysql]
+host = "127.0.0.1"
+`)
+	w, err := NewFileWatcher(filepath.Join(dir, "app.toml"), 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan Configs, 1)
+	w.Watch("redis", func(old, new Configs) {
+		changes <- new
+	})
+	// calls after the first one have no effect
+	w.Start()
+	w.Start()
+
+	write("common.toml", `
+[redis]
+addr = "localhost:6380"
+`)
+	select {
+	case cs := <-changes:
+		if got := cs["addr"]; got != "localhost:6380" {
+			t.Errorf("need: localhost:6380, got: %v\n", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload timeout")
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no reload after Close returns
+	write("common.toml", `
+[redis]
+addr = "localhost:6381"
+`)
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case cs := <-changes:
+		t.Errorf("need no reload after close, got: %v\n", cs)
+	default:
+	}
+}
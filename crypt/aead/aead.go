@@ -0,0 +1,233 @@
+// Package aead provides authenticated encryption with associated data, ciphertexts are versioned
+// envelopes so the algorithm is identified on decrypt:
+//
+//	+---------+-----------+-------+------------------------+
+//	| version | algorithm | nonce | ciphertext and tag     |
+//	| 1 byte  | 1 byte    |       |                        |
+//	+---------+-----------+-------+------------------------+
+//
+// the version and algorithm bytes are authenticated together with the associated data
+package aead
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+	"io"
+)
+
+// Version is the envelope version written by Seal
+const Version byte = 1
+
+// Algorithm identifies the AEAD algorithm in the envelope
+type Algorithm byte
+
+const (
+	// AESGCM is AES-GCM with 12 bytes random nonce, the key must be 16, 24 or 32 bytes
+	AESGCM Algorithm = 1
+	// XChaCha20Poly1305 is XChaCha20-Poly1305 with 24 bytes random nonce, the key must be 32 bytes
+	XChaCha20Poly1305 Algorithm = 2
+)
+
+func (alg Algorithm) String() string {
+	switch alg {
+	case AESGCM:
+		return "AES-GCM"
+	case XChaCha20Poly1305:
+		return "XChaCha20-Poly1305"
+	default:
+		return fmt.Sprintf("Algorithm(%d)", byte(alg))
+	}
+}
+
+var (
+	ErrInvalidKey           = errors.New("aead: invalid key size")
+	ErrCiphertextTooShort   = errors.New("aead: ciphertext too short")
+	ErrUnsupportedVersion   = errors.New("aead: unsupported envelope version")
+	ErrUnsupportedAlgorithm = errors.New("aead: unsupported algorithm")
+	ErrAlgorithmMismatch    = errors.New("aead: algorithm mismatch")
+	ErrAuthentication       = errors.New("aead: message authentication failed")
+)
+
+// headerSize is the size of version and algorithm bytes
+const headerSize = 2
+
+// Cipher seals and opens envelopes of a single algorithm and key
+type Cipher struct {
+	alg  Algorithm
+	aead cipher.AEAD
+}
+
+// NewCipher returns the cipher of alg, ErrInvalidKey is returned if the key size does not match alg
+func NewCipher(alg Algorithm, key []byte) (*Cipher, error) {
+	var (
+		aead cipher.AEAD
+		err  error
+	)
+	switch alg {
+	case AESGCM:
+		var block cipher.Block
+		block, err = aes.NewCipher(key)
+		if err != nil {
+			return nil, ErrInvalidKey
+		}
+		aead, err = cipher.NewGCM(block)
+	case XChaCha20Poly1305:
+		if len(key) != chacha20poly1305.KeySize {
+			return nil, ErrInvalidKey
+		}
+		aead, err = chacha20poly1305.NewX(key)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{alg: alg, aead: aead}, nil
+}
+
+// Algorithm returns the algorithm of c
+func (c *Cipher) Algorithm() Algorithm {
+	return c.alg
+}
+
+// Overhead returns the difference between the lengths of an envelope and its plaintext
+func (c *Cipher) Overhead() int {
+	return headerSize + c.aead.NonceSize() + c.aead.Overhead()
+}
+
+// NonceSize returns the nonce size of the algorithm
+func (c *Cipher) NonceSize() int {
+	return c.aead.NonceSize()
+}
+
+// Seal encrypts and authenticates plaintext and authenticates additionalData, a random nonce is
+// generated for every call
+func (c *Cipher) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return c.SealWithNonce(nonce, plaintext, additionalData)
+}
+
+// SealWithNonce is the same as Seal but uses the given nonce, a nonce must never be reused for
+// different plaintexts by the same key, it is used for deterministic encryption with synthetic nonces
+// derived from the plaintext
+func (c *Cipher) SealWithNonce(nonce, plaintext, additionalData []byte) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	if len(nonce) != nonceSize {
+		return nil, errors.New("aead: invalid nonce size")
+	}
+	out := make([]byte, headerSize+nonceSize, c.Overhead()+len(plaintext))
+	out[0], out[1] = Version, byte(c.alg)
+	copy(out[headerSize:], nonce)
+	return c.aead.Seal(out, out[headerSize:], plaintext, c.additionalData(out[:headerSize], additionalData)), nil
+}
+
+// Open authenticates and decrypts the envelope, additionalData must match the value passed to Seal
+func (c *Cipher) Open(envelope, additionalData []byte) ([]byte, error) {
+	alg, err := AlgorithmOf(envelope)
+	if err != nil {
+		return nil, err
+	}
+	if alg != c.alg {
+		return nil, ErrAlgorithmMismatch
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(envelope) < headerSize+nonceSize+c.aead.Overhead() {
+		return nil, ErrCiphertextTooShort
+	}
+	nonce, ciphertext := envelope[headerSize:headerSize+nonceSize], envelope[headerSize+nonceSize:]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, c.additionalData(envelope[:headerSize], additionalData))
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	return plaintext, nil
+}
+
+// SealString is the same as Seal but returns base64url encoded string, the same encoding as
+// aes.AesCBCEncrypt
+func (c *Cipher) SealString(plaintext, additionalData []byte) (string, error) {
+	envelope, err := c.Seal(plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(envelope), nil
+}
+
+// OpenString opens the base64url encoded envelope
+func (c *Cipher) OpenString(data string, additionalData []byte) ([]byte, error) {
+	envelope, err := base64.URLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(envelope, additionalData)
+}
+
+// additionalData binds the header to the associated data
+func (c *Cipher) additionalData(header, additionalData []byte) []byte {
+	ad := make([]byte, 0, len(header)+len(additionalData))
+	return append(append(ad, header...), additionalData...)
+}
+
+// AlgorithmOf returns the algorithm of the envelope
+func AlgorithmOf(envelope []byte) (Algorithm, error) {
+	if len(envelope) < headerSize {
+		return 0, ErrCiphertextTooShort
+	}
+	if envelope[0] != Version {
+		return 0, ErrUnsupportedVersion
+	}
+	alg := Algorithm(envelope[1])
+	switch alg {
+	case AESGCM, XChaCha20Poly1305:
+		return alg, nil
+	default:
+		return 0, ErrUnsupportedAlgorithm
+	}
+}
+
+// Seal encrypts plaintext with alg and key
+func Seal(alg Algorithm, key, plaintext, additionalData []byte) ([]byte, error) {
+	c, err := NewCipher(alg, key)
+	if err != nil {
+		return nil, err
+	}
+	return c.Seal(plaintext, additionalData)
+}
+
+// Open decrypts the envelope with key, the algorithm is read from the envelope
+func Open(key, envelope, additionalData []byte) ([]byte, error) {
+	alg, err := AlgorithmOf(envelope)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewCipher(alg, key)
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(envelope, additionalData)
+}
+
+// SealString encrypts plaintext with alg and key and returns base64url encoded string
+func SealString(alg Algorithm, key, plaintext, additionalData []byte) (string, error) {
+	c, err := NewCipher(alg, key)
+	if err != nil {
+		return "", err
+	}
+	return c.SealString(plaintext, additionalData)
+}
+
+// OpenString decrypts the base64url encoded envelope with key
+func OpenString(data string, key, additionalData []byte) ([]byte, error) {
+	envelope, err := base64.URLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	return Open(key, envelope, additionalData)
+}
@@ -0,0 +1,64 @@
+package aead
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	message := []byte("hello world!")
+	ad := []byte("admin:1")
+	for _, alg := range []Algorithm{AESGCM, XChaCha20Poly1305} {
+		key := bytes.Repeat([]byte("k"), 32)
+		data, err := SealString(alg, key, message, ad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := OpenString(data, key, ad)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if !bytes.Equal(message, got) {
+			t.Errorf("%s: need: %s, got: %s\n", alg, message, got)
+		}
+		_, err = OpenString(data, key, []byte("admin:2"))
+		if err != ErrAuthentication {
+			t.Errorf("%s: need: %v, got: %v\n", alg, ErrAuthentication, err)
+		}
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	key := []byte("change this pass")
+	envelope, err := Seal(AESGCM, key, []byte("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte(nil), envelope...)
+	tampered[len(tampered)-1] ^= 1
+	version := append([]byte(nil), envelope...)
+	version[0] = 9
+	alg := append([]byte(nil), envelope...)
+	alg[1] = byte(XChaCha20Poly1305)
+	tests := []struct {
+		envelope []byte
+		err      error
+	}{
+		{envelope: nil, err: ErrCiphertextTooShort},
+		{envelope: envelope[:10], err: ErrCiphertextTooShort},
+		{envelope: tampered, err: ErrAuthentication},
+		{envelope: version, err: ErrUnsupportedVersion},
+		// XChaCha20-Poly1305 needs 32 bytes key
+		{envelope: alg, err: ErrInvalidKey},
+	}
+	for i, test := range tests {
+		_, err := Open(key, test.envelope, nil)
+		if err != test.err {
+			t.Errorf("%d: need: %v, got: %v\n", i, test.err, err)
+		}
+	}
+	_, err = NewCipher(AESGCM, []byte("short"))
+	if err != ErrInvalidKey {
+		t.Errorf("need: %v, got: %v\n", ErrInvalidKey, err)
+	}
+}
@@ -5,33 +5,68 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"io"
 )
 
+var (
+	ErrInvalidKey           = errors.New("aes: invalid key size, must be 16, 24 or 32 bytes")
+	ErrCiphertextTooShort   = errors.New("aes: ciphertext too short")
+	ErrCiphertextNotAligned = errors.New("aes: ciphertext is not a multiple of the block size")
+	ErrBadPadding           = errors.New("aes: bad padding")
+)
+
 func PKCS7Padding(ciphertext []byte, blockSize int) []byte {
 	padding := blockSize - len(ciphertext)%blockSize
 	text := bytes.Repeat([]byte{byte(padding)}, padding)
 	return append(ciphertext, text...)
 }
 
-func PKCS7UnPadding(origData []byte) []byte {
+// PKCS7UnPadding removes the padding, ErrBadPadding is returned if the padding is malformed, the
+// padding is validated in constant time
+func PKCS7UnPadding(origData []byte) ([]byte, error) {
+	return pkcs7UnPadding(origData, 255)
+}
+
+// pkcs7UnPadding validates the padding without branching on the padding bytes, so the time does not
+// leak which byte is wrong, the padding must not be longer than maxPadding
+func pkcs7UnPadding(origData []byte, maxPadding int) ([]byte, error) {
 	length := len(origData)
-	text := int(origData[length-1])
-	return origData[:(length - text)]
+	if length == 0 {
+		return nil, ErrBadPadding
+	}
+	padding := int(origData[length-1])
+	good := subtle.ConstantTimeLessOrEq(1, padding) &
+		subtle.ConstantTimeLessOrEq(padding, maxPadding) &
+		subtle.ConstantTimeLessOrEq(padding, length)
+	toCheck := maxPadding
+	if toCheck > length {
+		toCheck = length
+	}
+	for i := 1; i <= toCheck; i++ {
+		inPadding := subtle.ConstantTimeLessOrEq(i, padding)
+		equal := subtle.ConstantTimeByteEq(origData[length-i], byte(padding))
+		good &= subtle.ConstantTimeSelect(inPadding, equal, 1)
+	}
+	if good != 1 {
+		return nil, ErrBadPadding
+	}
+	return origData[:length-padding], nil
 }
 
 func AesCBCEncrypt(rawData, key []byte) (string, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		panic(err)
+		return "", ErrInvalidKey
 	}
 	blockSize := block.BlockSize()
 	rawData = PKCS7Padding(rawData, blockSize)
 	cipherText := make([]byte, blockSize+len(rawData))
 	iv := cipherText[:blockSize]
 	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		panic(err)
+		return "", err
 	}
 	mode := cipher.NewCBCEncrypter(block, iv)
 	mode.CryptBlocks(cipherText[blockSize:], rawData)
@@ -45,25 +80,25 @@ func AesCBCDecrypt(data string, key []byte) ([]byte, error) {
 	}
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidKey
 	}
 
 	blockSize := block.BlockSize()
 
-	if len(encryptData) < blockSize {
-		panic("ciphertext too short")
+	// the iv and at least one block of padded data
+	if len(encryptData) < 2*blockSize {
+		return nil, ErrCiphertextTooShort
 	}
 	iv := encryptData[:blockSize]
 	encryptData = encryptData[blockSize:]
 
 	// CBC mode always works in whole blocks.
 	if len(encryptData)%blockSize != 0 {
-		panic("ciphertext is not a multiple of the block size")
+		return nil, ErrCiphertextNotAligned
 	}
 
 	mode := cipher.NewCBCDecrypter(block, iv)
 
 	mode.CryptBlocks(encryptData, encryptData)
-	encryptData = PKCS7UnPadding(encryptData)
-	return encryptData, nil
+	return pkcs7UnPadding(encryptData, blockSize)
 }
@@ -1,6 +1,9 @@
 package aes
 
 import (
+	"bytes"
+	"crypto/aes"
+	"encoding/base64"
 	"testing"
 )
 
@@ -19,3 +22,73 @@ func TestAesCBCDecrypt(t *testing.T) {
 		t.Errorf("need: %s, got: %s\n", need, got)
 	}
 }
+
+func TestAesCBCDecryptErrors(t *testing.T) {
+	key := []byte("change this pass")
+	block := base64.URLEncoding.EncodeToString(make([]byte, 16))
+	tests := []struct {
+		data string
+		key  []byte
+		err  error
+	}{
+		{data: block, key: []byte("short"), err: ErrInvalidKey},
+		{data: "", key: key, err: ErrCiphertextTooShort},
+		{data: block, key: key, err: ErrCiphertextTooShort},
+		{data: base64.URLEncoding.EncodeToString(make([]byte, 40)), key: key, err: ErrCiphertextNotAligned},
+	}
+	for i, test := range tests {
+		_, err := AesCBCDecrypt(test.data, test.key)
+		if err != test.err {
+			t.Errorf("%d: need: %v, got: %v\n", i, test.err, err)
+		}
+	}
+	_, err := AesCBCEncrypt([]byte("hello"), []byte("short"))
+	if err != ErrInvalidKey {
+		t.Errorf("need: %v, got: %v\n", ErrInvalidKey, err)
+	}
+}
+
+func TestPKCS7UnPadding(t *testing.T) {
+	tests := []struct {
+		data []byte
+		need []byte
+		err  error
+	}{
+		{data: []byte{'a', 'b', 2, 2}, need: []byte{'a', 'b'}},
+		{data: []byte{4, 4, 4, 4}, need: []byte{}},
+		{data: nil, err: ErrBadPadding},
+		{data: []byte{'a', 'b', 0}, err: ErrBadPadding},
+		{data: []byte{'a', 'b', 1, 2}, err: ErrBadPadding},
+		{data: []byte{'a', 5}, err: ErrBadPadding},
+	}
+	for i, test := range tests {
+		got, err := PKCS7UnPadding(test.data)
+		if err != test.err || !bytes.Equal(got, test.need) {
+			t.Errorf("%d: need: %v %v, got: %v %v\n", i, test.need, test.err, got, err)
+		}
+	}
+}
+
+// FuzzAesCBCDecrypt decrypts arbitrary ciphertexts, none of them may panic or return plaintext on error:
+//
+//	go test -fuzz FuzzAesCBCDecrypt ./crypt/aes
+func FuzzAesCBCDecrypt(f *testing.F) {
+	key := []byte("change this pass")
+	valid, err := AesCBCEncrypt([]byte("hello world!"), key)
+	if err != nil {
+		f.Fatal(err)
+	}
+	raw, _ := base64.URLEncoding.DecodeString(valid)
+	f.Add(raw)
+	f.Add([]byte{})
+	f.Add(raw[:aes.BlockSize])
+	tampered := append([]byte(nil), raw...)
+	tampered[len(tampered)-aes.BlockSize-1] ^= 1
+	f.Add(tampered)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		plaintext, err := AesCBCDecrypt(base64.URLEncoding.EncodeToString(data), key)
+		if err != nil && plaintext != nil {
+			t.Fatalf("plaintext is not nil on error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,421 @@
+// Package envelope encrypts database columns with envelope encryption, values are encrypted by a
+// random data key and the data key is wrapped by the master key ring. After the master key is rotated,
+// new values are wrapped by the new master key and old values stay readable as long as the master key
+// which wrapped their data key is kept in the ring, use Reencrypt to move the stored values to the
+// active master key before removing an old one. An encrypted value is the base64url encoding of:
+//
+//	+---------+--------------------+------------------+---------------------------+
+//	| version | wrapped key length | wrapped data key | aead envelope of value    |
+//	| 1 byte  | 2 bytes            | keyring format   | encrypted by the data key |
+//	+---------+--------------------+------------------+---------------------------+
+//
+// EncryptedString and DeterministicString are gorm data types which encrypt on write and decrypt on
+// scan by the encryptor set by SetEncryptor, a DeterministicString is bound to the column named by its
+// type parameter:
+//
+//	type EmailColumn struct{}
+//
+//	func (EmailColumn) Column() string { return "email" }
+//
+//	type Admin struct {
+//		ID    int
+//		Phone envelope.EncryptedString
+//		Email envelope.DeterministicString[EmailColumn] `gorm:"index"`
+//	}
+//
+//	db.Where(&Admin{Email: "admin@example.com"}).First(&admin)
+//
+// Deterministic values and blind indexes are keyed by the index key only, they are not wrapped by the
+// master key ring, so rotating the master key does not cover them, and changing the index key requires
+// re-encrypting the deterministic values and rebuilding the blind indexes.
+package envelope
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/morgine/pkg/crypt/aead"
+	"github.com/morgine/pkg/crypt/keyring"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"sync"
+)
+
+// Version is the format version written by Encrypt
+const Version byte = 1
+
+var (
+	ErrNoEncryptor        = errors.New("envelope: encryptor is not set")
+	ErrInvalidCiphertext  = errors.New("envelope: invalid ciphertext")
+	ErrUnsupportedVersion = errors.New("envelope: unsupported version")
+	ErrInvalidIndexKey    = errors.New("envelope: index key must be at least 16 bytes")
+)
+
+// dataKeyAD binds the wrapped data keys to their usage
+var dataKeyAD = []byte("envelope data key")
+
+// Encryptor encrypts values by a data key wrapped by the master key ring, it is safe for concurrent use
+type Encryptor struct {
+	master *keyring.KeyRing
+
+	mu      sync.RWMutex
+	dataKey *aead.Cipher
+	wrapped []byte
+	cache   map[string]*aead.Cipher
+
+	indexKey  []byte
+	detCipher *aead.Cipher
+}
+
+// NewEncryptor returns the encryptor of the master key ring, indexKey is the secret of blind indexes
+// and deterministic encryption, it must be the same for all processes sharing the database and should
+// be at least 32 random bytes
+func NewEncryptor(master *keyring.KeyRing, indexKey []byte) (*Encryptor, error) {
+	if len(indexKey) < 16 {
+		return nil, ErrInvalidIndexKey
+	}
+	e := &Encryptor{
+		master:   master,
+		cache:    make(map[string]*aead.Cipher),
+		indexKey: derive(indexKey, "index"),
+	}
+	var err error
+	e.detCipher, err = aead.NewCipher(aead.AESGCM, derive(indexKey, "deterministic"))
+	if err != nil {
+		return nil, err
+	}
+	err = e.RotateDataKey()
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// RotateDataKey generates a new data key wrapped by the active master key, new values are encrypted by
+// the new data key and the values of the old data keys can still be decrypted
+func (e *Encryptor) RotateDataKey() error {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return err
+	}
+	c, err := aead.NewCipher(aead.AESGCM, key)
+	if err != nil {
+		return err
+	}
+	wrapped, err := e.master.Seal(key, dataKeyAD)
+	if err != nil {
+		return err
+	}
+	if len(wrapped) > 0xffff {
+		return errors.New("envelope: wrapped data key too long")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dataKey, e.wrapped = c, wrapped
+	e.cache[string(wrapped)] = c
+	return nil
+}
+
+// Encrypt encrypts plaintext by the data key, additionalData must be passed to Decrypt too, a new data
+// key is generated if the current data key is not wrapped by the active master key, so new values
+// follow the rotation of the master key ring
+func (e *Encryptor) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	e.mu.RLock()
+	c, wrapped := e.dataKey, e.wrapped
+	e.mu.RUnlock()
+	if id, _ := keyring.KeyID(wrapped); id != e.master.Active() {
+		if err := e.RotateDataKey(); err != nil {
+			return nil, err
+		}
+		e.mu.RLock()
+		c, wrapped = e.dataKey, e.wrapped
+		e.mu.RUnlock()
+	}
+	sealed, err := c.Seal(plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 3, 3+len(wrapped)+len(sealed))
+	out[0] = Version
+	binary.BigEndian.PutUint16(out[1:3], uint16(len(wrapped)))
+	out = append(out, wrapped...)
+	return append(out, sealed...), nil
+}
+
+// Decrypt unwraps the data key by the master key ring and decrypts the value, unwrapped data keys are
+// cached until their master key is removed from the ring
+func (e *Encryptor) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < 3 {
+		return nil, ErrInvalidCiphertext
+	}
+	if ciphertext[0] != Version {
+		return nil, ErrUnsupportedVersion
+	}
+	size := 3 + int(binary.BigEndian.Uint16(ciphertext[1:3]))
+	if len(ciphertext) < size {
+		return nil, ErrInvalidCiphertext
+	}
+	c, err := e.unwrap(ciphertext[3:size])
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(ciphertext[size:], additionalData)
+}
+
+// unwrap returns the cipher of the wrapped data key, cached keys of removed master keys are evicted
+func (e *Encryptor) unwrap(wrapped []byte) (*aead.Cipher, error) {
+	id, err := keyring.KeyID(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: unwrap data key: %w", err)
+	}
+	if !e.master.Has(id) {
+		e.mu.Lock()
+		delete(e.cache, string(wrapped))
+		e.mu.Unlock()
+		return nil, fmt.Errorf("envelope: unwrap data key: %w", keyring.ErrUnknownKey)
+	}
+	e.mu.RLock()
+	c := e.cache[string(wrapped)]
+	e.mu.RUnlock()
+	if c != nil {
+		return c, nil
+	}
+	key, err := e.master.Open(wrapped, dataKeyAD)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: unwrap data key: %w", err)
+	}
+	c, err = aead.NewCipher(aead.AESGCM, key)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.cache[string(wrapped)] = c
+	e.mu.Unlock()
+	return c, nil
+}
+
+// NeedsReencrypt reports whether the data key of ciphertext is not wrapped by the active master key
+func (e *Encryptor) NeedsReencrypt(ciphertext []byte) bool {
+	if len(ciphertext) < 3 || ciphertext[0] != Version {
+		return true
+	}
+	size := 3 + int(binary.BigEndian.Uint16(ciphertext[1:3]))
+	if len(ciphertext) < size {
+		return true
+	}
+	id, err := keyring.KeyID(ciphertext[3:size])
+	return err != nil || id != e.master.Active()
+}
+
+// Reencrypt decrypts ciphertext and encrypts it by the data key of the active master key, the values
+// of an old master key must be re-encrypted before the key is removed from the ring
+func (e *Encryptor) Reencrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	plaintext, err := e.Decrypt(ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return e.Encrypt(plaintext, additionalData)
+}
+
+// ReencryptString is the same as Reencrypt for the strings of EncryptString
+func (e *Encryptor) ReencryptString(data string) (string, error) {
+	value, err := e.DecryptString(data)
+	if err != nil {
+		return "", err
+	}
+	return e.EncryptString(value)
+}
+
+// EncryptString encrypts value and returns the base64url encoded string
+func (e *Encryptor) EncryptString(value string) (string, error) {
+	data, err := e.Encrypt([]byte(value), nil)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// DecryptString decrypts the base64url encoded string of EncryptString
+func (e *Encryptor) DecryptString(data string) (string, error) {
+	ciphertext, err := base64.URLEncoding.DecodeString(data)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	value, err := e.Decrypt(ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// BlindIndex returns the keyed hash of value for column, it can be stored in an indexed column beside
+// an EncryptedString to look up the value by equality, the hash of the same value differs by column
+func (e *Encryptor) BlindIndex(column, value string) string {
+	mac := hmac.New(sha256.New, e.indexKey)
+	mac.Write([]byte(column))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// EncryptDeterministic encrypts value of column deterministically, the nonce is derived from column and
+// value, so equal values of a column have equal ciphertexts, it reveals which values are equal and
+// should only be used for columns which are looked up by equality. The column is authenticated as
+// associated data, so the ciphertexts of equal values differ by column. The key is derived from the
+// index key and is not covered by the rotation of the master key ring
+func (e *Encryptor) EncryptDeterministic(column, value string) (string, error) {
+	mac := hmac.New(sha256.New, e.indexKey)
+	mac.Write([]byte("deterministic nonce"))
+	mac.Write([]byte(column))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	sum := mac.Sum(nil)
+	// the synthetic nonce replaces the random nonce of the aead envelope
+	sealed, err := e.detCipher.SealWithNonce(sum[:e.detCipher.NonceSize()], []byte(value), []byte(column))
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptDeterministic decrypts the value of column encrypted by EncryptDeterministic
+func (e *Encryptor) DecryptDeterministic(column, data string) (string, error) {
+	ciphertext, err := base64.URLEncoding.DecodeString(data)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	value, err := e.detCipher.Open(ciphertext, []byte(column))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// derive derives the sub key of purpose from secret
+func derive(secret []byte, purpose string) []byte {
+	key := make([]byte, 32)
+	_, _ = io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte("envelope "+purpose)), key)
+	return key
+}
+
+var encryptor = struct {
+	sync.RWMutex
+	e *Encryptor
+}{}
+
+// SetEncryptor sets the encryptor of EncryptedString and DeterministicString
+func SetEncryptor(e *Encryptor) {
+	encryptor.Lock()
+	defer encryptor.Unlock()
+	encryptor.e = e
+}
+
+func getEncryptor() (*Encryptor, error) {
+	encryptor.RLock()
+	defer encryptor.RUnlock()
+	if encryptor.e == nil {
+		return nil, ErrNoEncryptor
+	}
+	return encryptor.e, nil
+}
+
+// EncryptedString is a string column which is encrypted on write and decrypted on scan, the
+// ciphertexts of equal values differ, use DeterministicString or BlindIndex for lookups
+type EncryptedString string
+
+// GormDataType stores the ciphertext in a string column
+func (EncryptedString) GormDataType() string {
+	return "string"
+}
+
+// Value encrypts s
+func (s EncryptedString) Value() (driver.Value, error) {
+	e, err := getEncryptor()
+	if err != nil {
+		return nil, err
+	}
+	return e.EncryptString(string(s))
+}
+
+// Scan decrypts the column value, NULL is scanned as empty string
+func (s *EncryptedString) Scan(src interface{}) error {
+	data, ok, err := scanString(src)
+	if err != nil || !ok {
+		*s = ""
+		return err
+	}
+	e, err := getEncryptor()
+	if err != nil {
+		return err
+	}
+	value, err := e.DecryptString(data)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(value)
+	return nil
+}
+
+// Column names the column of a DeterministicString, it is usually implemented by an empty struct
+type Column interface {
+	Column() string
+}
+
+// DeterministicString is a string column which is encrypted deterministically, so it can be looked up
+// by equality, such as db.Where(&Admin{Email: "admin@example.com"}). The column of C is authenticated
+// as associated data, so equal values of different columns have different ciphertexts, it is not
+// covered by the rotation of the master key ring
+type DeterministicString[C Column] string
+
+// GormDataType stores the ciphertext in a string column
+func (DeterministicString[C]) GormDataType() string {
+	return "string"
+}
+
+// Value encrypts s
+func (s DeterministicString[C]) Value() (driver.Value, error) {
+	e, err := getEncryptor()
+	if err != nil {
+		return nil, err
+	}
+	var c C
+	return e.EncryptDeterministic(c.Column(), string(s))
+}
+
+// Scan decrypts the column value, NULL is scanned as empty string
+func (s *DeterministicString[C]) Scan(src interface{}) error {
+	data, ok, err := scanString(src)
+	if err != nil || !ok {
+		*s = ""
+		return err
+	}
+	e, err := getEncryptor()
+	if err != nil {
+		return err
+	}
+	var c C
+	value, err := e.DecryptDeterministic(c.Column(), data)
+	if err != nil {
+		return err
+	}
+	*s = DeterministicString[C](value)
+	return nil
+}
+
+func scanString(src interface{}) (string, bool, error) {
+	switch tv := src.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return tv, true, nil
+	case []byte:
+		return string(tv), true, nil
+	default:
+		return "", false, fmt.Errorf("envelope: can not scan %T", src)
+	}
+}
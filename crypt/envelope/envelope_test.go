@@ -0,0 +1,202 @@
+package envelope
+
+import (
+	"bytes"
+	"errors"
+	"github.com/morgine/pkg/crypt/aead"
+	"github.com/morgine/pkg/crypt/keyring"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"strings"
+	"testing"
+)
+
+type emailColumn struct{}
+
+func (emailColumn) Column() string { return "email" }
+
+type nameColumn struct{}
+
+func (nameColumn) Column() string { return "name" }
+
+type user struct {
+	ID         int
+	Phone      EncryptedString
+	Email      DeterministicString[emailColumn] `gorm:"index"`
+	Name       DeterministicString[nameColumn]
+	EmailIndex string `gorm:"index"`
+}
+
+func newEncryptor(t *testing.T) (*Encryptor, *keyring.KeyRing) {
+	master := keyring.New()
+	if err := master.Add("m1", aead.AESGCM, bytes.Repeat([]byte("1"), 32)); err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEncryptor(master, []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e, master
+}
+
+func TestEncryptor(t *testing.T) {
+	e, master := newEncryptor(t)
+	old, err := e.EncryptString("13800000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rotate the master key and the data key, the old values can still be decrypted
+	if err := master.Rotate("m2", aead.AESGCM, bytes.Repeat([]byte("2"), 32)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.RotateDataKey(); err != nil {
+		t.Fatal(err)
+	}
+	current, err := e.EncryptString("13800000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old == current {
+		t.Error("need different ciphertexts")
+	}
+	for _, data := range []string{old, current} {
+		if value, err := e.DecryptString(data); err != nil || value != "13800000000" {
+			t.Errorf("need: 13800000000, got: %s %v\n", value, err)
+		}
+	}
+	// the values of a removed master key can not be decrypted, even if the data key was cached
+	if err := master.Remove("m1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.DecryptString(old); !errors.Is(err, keyring.ErrUnknownKey) {
+		t.Errorf("need: %v, got: %v\n", keyring.ErrUnknownKey, err)
+	}
+	if value, err := e.DecryptString(current); err != nil || value != "13800000000" {
+		t.Errorf("need: 13800000000, got: %s %v\n", value, err)
+	}
+	if e.BlindIndex("email", "a@b.com") == e.BlindIndex("name", "a@b.com") {
+		t.Error("need different blind indexes of columns")
+	}
+	email, _ := e.EncryptDeterministic("email", "a@b.com")
+	again, _ := e.EncryptDeterministic("email", "a@b.com")
+	name, _ := e.EncryptDeterministic("name", "a@b.com")
+	if email != again || email == name {
+		t.Errorf("need equal ciphertexts of a column only, got: %s %s %s\n", email, again, name)
+	}
+	if value, err := e.DecryptDeterministic("email", email); err != nil || value != "a@b.com" {
+		t.Errorf("need: a@b.com, got: %s %v\n", value, err)
+	}
+	if _, err := e.DecryptDeterministic("name", email); err == nil {
+		t.Error("need error of another column")
+	}
+}
+
+func TestEncryptAfterRotate(t *testing.T) {
+	e, master := newEncryptor(t)
+	if err := master.Rotate("m2", aead.AESGCM, bytes.Repeat([]byte("2"), 32)); err != nil {
+		t.Fatal(err)
+	}
+	// the next value is wrapped by the new master key without calling RotateDataKey
+	data, err := e.Encrypt([]byte("13800000000"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := 3 + int(data[1])<<8 + int(data[2])
+	if id, err := keyring.KeyID(data[3:size]); err != nil || id != "m2" {
+		t.Errorf("need: m2, got: %s %v\n", id, err)
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	e, master := newEncryptor(t)
+	old, err := e.Encrypt([]byte("13800000000"), []byte("phone"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := master.Rotate("m2", aead.AESGCM, bytes.Repeat([]byte("2"), 32)); err != nil {
+		t.Fatal(err)
+	}
+	if !e.NeedsReencrypt(old) {
+		t.Error("need re-encryption of the m1 value")
+	}
+	current, err := e.Reencrypt(old, []byte("phone"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.NeedsReencrypt(current) {
+		t.Error("need no re-encryption of the m2 value")
+	}
+	// the re-encrypted value survives the removal of the old master key
+	if err := master.Remove("m1"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := e.Decrypt(current, []byte("phone")); err != nil || string(value) != "13800000000" {
+		t.Errorf("need: 13800000000, got: %s %v\n", value, err)
+	}
+}
+
+func TestEncryptAfterRemove(t *testing.T) {
+	e, master := newEncryptor(t)
+	if err := master.Rotate("m2", aead.AESGCM, bytes.Repeat([]byte("2"), 32)); err != nil {
+		t.Fatal(err)
+	}
+	if err := master.Remove("m1"); err != nil {
+		t.Fatal(err)
+	}
+	// the data key wrapped by m1 is replaced, so the new values stay decryptable
+	data, err := e.EncryptString("13800000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := e.DecryptString(data); err != nil || value != "13800000000" {
+		t.Errorf("need: 13800000000, got: %s %v\n", value, err)
+	}
+}
+
+func TestGormDataType(t *testing.T) {
+	e, _ := newEncryptor(t)
+	SetEncryptor(e)
+	defer SetEncryptor(nil)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&user{}); err != nil {
+		t.Fatal(err)
+	}
+	u := &user{Phone: "13800000000", Email: "a@b.com", Name: "a@b.com", EmailIndex: e.BlindIndex("email", "a@b.com")}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Phone string
+		Email string
+		Name  string
+	}
+	if err := db.Table("users").Select("phone, email, name").Scan(&raw).Error; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(raw.Phone, "138") || strings.Contains(raw.Email, "@") {
+		t.Errorf("need encrypted columns, got: %+v\n", raw)
+	}
+	// equal values of different columns have different ciphertexts
+	if raw.Email == raw.Name {
+		t.Errorf("need different ciphertexts of columns, got: %+v\n", raw)
+	}
+
+	var got user
+	if err := db.Where(&user{Email: "a@b.com"}).First(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Phone != "13800000000" || got.Email != "a@b.com" || got.Name != "a@b.com" {
+		t.Errorf("need decrypted columns, got: %+v\n", got)
+	}
+	got = user{}
+	if err := db.Where("email_index = ?", e.BlindIndex("email", "a@b.com")).First(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != u.ID {
+		t.Errorf("need: %d, got: %d\n", u.ID, got.ID)
+	}
+}
@@ -0,0 +1,372 @@
+// Package jose issues and verifies JWS tokens signed by HS256, RS256 or EdDSA, and JWE tokens
+// encrypted by direct key agreement with A256GCM, tokens are in the compact serialization so they can
+// be parsed by other services and frontends. The public keys of a KeySet can be exported as JWKS, so
+// other services verify the tokens without sharing any secret.
+package jose
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Now returns the current time, it is replaced by tests
+var Now = time.Now
+
+// Algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	ErrMalformed   = errors.New("jose: malformed token")
+	ErrUnknownKey  = errors.New("jose: unknown key")
+	ErrAlgorithm   = errors.New("jose: algorithm mismatch")
+	ErrSignature   = errors.New("jose: bad signature")
+	ErrDecryption  = errors.New("jose: decryption failed")
+	ErrExpired     = errors.New("jose: token expired")
+	ErrNotValidYet = errors.New("jose: token not valid yet")
+	ErrAudience    = errors.New("jose: audience mismatch")
+	ErrVerifyOnly  = errors.New("jose: key can only verify")
+	ErrInvalidKey  = errors.New("jose: invalid key")
+	ErrUnsupported = errors.New("jose: unsupported algorithm")
+	ErrIssuer      = errors.New("jose: issuer mismatch")
+	ErrWeakSecret  = errors.New("jose: HS256 secret must be at least 32 bytes")
+)
+
+var b64 = base64.RawURLEncoding
+
+// Audience is the "aud" claim, it is a single string or a list of strings in JSON
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Contains reports whether aud is in a
+func (a Audience) Contains(aud string) bool {
+	for _, item := range a {
+		if item == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims are the registered claims, custom claims can be added by embedding Claims:
+//
+//	type AdminClaims struct {
+//		jose.Claims
+//		Role string `json:"role"`
+//	}
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+}
+
+// NewClaims returns the claims of subject issued now, expires after ttl and identified by a random ID
+func NewClaims(subject string, ttl time.Duration, audience ...string) Claims {
+	now := Now()
+	c := Claims{Subject: subject, IssuedAt: now.Unix(), ID: randomID()}
+	if ttl > 0 {
+		c.ExpiresAt = now.Add(ttl).Unix()
+	}
+	if len(audience) > 0 {
+		c.Audience = audience
+	}
+	return c
+}
+
+// Expectation is checked by Verify and Decrypt besides the signature or the authentication tag
+type Expectation struct {
+	Issuer   string        // expected "iss", not checked if empty
+	Audience string        // expected "aud", not checked if empty
+	Leeway   time.Duration // tolerated clock skew of "exp" and "nbf"
+	// Now returns the current time to check "exp" and "nbf", the package Now is used if nil
+	Now func() time.Time
+}
+
+// Validate checks the time claims and the expectation
+func (c Claims) Validate(expect Expectation) error {
+	now := Now()
+	if expect.Now != nil {
+		now = expect.Now()
+	}
+	if c.ExpiresAt > 0 && !now.Before(time.Unix(c.ExpiresAt, 0).Add(expect.Leeway)) {
+		return ErrExpired
+	}
+	if c.NotBefore > 0 && now.Add(expect.Leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return ErrNotValidYet
+	}
+	if expect.Issuer != "" && c.Issuer != expect.Issuer {
+		return ErrIssuer
+	}
+	if expect.Audience != "" && !c.Audience.Contains(expect.Audience) {
+		return ErrAudience
+	}
+	return nil
+}
+
+// Key is a signing or verifying key identified by ID
+type Key struct {
+	ID         string
+	Algorithm  string
+	secret     []byte
+	rsaPrivate *rsa.PrivateKey
+	rsaPublic  *rsa.PublicKey
+	edPrivate  ed25519.PrivateKey
+	edPublic   ed25519.PublicKey
+}
+
+// HS256MinSecret is the minimum size of HS256 secrets
+const HS256MinSecret = 32
+
+// NewHS256 returns the HMAC-SHA256 key of secret, the secret must be at least HS256MinSecret bytes
+func NewHS256(id string, secret []byte) (*Key, error) {
+	if len(secret) < HS256MinSecret {
+		return nil, ErrWeakSecret
+	}
+	return &Key{ID: id, Algorithm: HS256, secret: secret}, nil
+}
+
+// NewRS256 returns the RSA signing key
+func NewRS256(id string, private *rsa.PrivateKey) *Key {
+	return &Key{ID: id, Algorithm: RS256, rsaPrivate: private, rsaPublic: &private.PublicKey}
+}
+
+// NewRS256Public returns the RSA verifying key
+func NewRS256Public(id string, public *rsa.PublicKey) *Key {
+	return &Key{ID: id, Algorithm: RS256, rsaPublic: public}
+}
+
+// NewEdDSA returns the Ed25519 signing key
+func NewEdDSA(id string, private ed25519.PrivateKey) *Key {
+	return &Key{ID: id, Algorithm: EdDSA, edPrivate: private, edPublic: private.Public().(ed25519.PublicKey)}
+}
+
+// NewEdDSAPublic returns the Ed25519 verifying key
+func NewEdDSAPublic(id string, public ed25519.PublicKey) *Key {
+	return &Key{ID: id, Algorithm: EdDSA, edPublic: public}
+}
+
+func (k *Key) sign(input []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case HS256:
+		if len(k.secret) == 0 {
+			return nil, ErrInvalidKey
+		}
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case RS256:
+		if k.rsaPrivate == nil {
+			return nil, ErrVerifyOnly
+		}
+		sum := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, k.rsaPrivate, crypto.SHA256, sum[:])
+	case EdDSA:
+		if k.edPrivate == nil {
+			return nil, ErrVerifyOnly
+		}
+		return ed25519.Sign(k.edPrivate, input), nil
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+func (k *Key) verify(input, signature []byte) bool {
+	switch k.Algorithm {
+	case HS256:
+		// a key without secret must not accept tokens signed by an empty key
+		if len(k.secret) == 0 {
+			return false
+		}
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case RS256:
+		sum := sha256.Sum256(input)
+		return k.rsaPublic != nil && rsa.VerifyPKCS1v15(k.rsaPublic, crypto.SHA256, sum[:], signature) == nil
+	case EdDSA:
+		return len(k.edPublic) == ed25519.PublicKeySize && ed25519.Verify(k.edPublic, input, signature)
+	default:
+		return false
+	}
+}
+
+// header is the JOSE header
+type header struct {
+	Algorithm  string `json:"alg"`
+	Encryption string `json:"enc,omitempty"`
+	KeyID      string `json:"kid,omitempty"`
+	Type       string `json:"typ,omitempty"`
+}
+
+// Sign returns the JWS compact serialization of claims signed by key, claims is usually Claims or a
+// struct embedding Claims
+func Sign(key *Key, claims interface{}) (string, error) {
+	h, err := json.Marshal(header{Algorithm: key.Algorithm, KeyID: key.ID, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := b64.EncodeToString(h) + "." + b64.EncodeToString(payload)
+	signature, err := key.sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + b64.EncodeToString(signature), nil
+}
+
+// KeySet holds the keys to verify tokens by key ID, it is safe for concurrent use
+type KeySet struct {
+	mu   sync.RWMutex
+	keys map[string]*Key
+}
+
+// NewKeySet returns the key set of keys
+func NewKeySet(keys ...*Key) *KeySet {
+	ks := &KeySet{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		ks.Add(key)
+	}
+	return ks
+}
+
+// Add adds or replaces the key of key.ID
+func (ks *KeySet) Add(key *Key) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[key.ID] = key
+}
+
+// Remove removes the key of id
+func (ks *KeySet) Remove(id string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	delete(ks.keys, id)
+}
+
+// Lookup returns the key of id
+func (ks *KeySet) Lookup(id string) (*Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[id]
+	return key, ok
+}
+
+// Verify verifies the JWS token, decodes its payload into claims and validates the registered claims,
+// the algorithm of the key is enforced, the "alg" header must match it
+func (ks *KeySet) Verify(token string, claims interface{}, expect Expectation) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrMalformed
+	}
+	h, err := decodeHeader(parts[0])
+	if err != nil {
+		return err
+	}
+	key, ok := ks.Lookup(h.KeyID)
+	if !ok {
+		return ErrUnknownKey
+	}
+	if h.Algorithm != key.Algorithm {
+		return ErrAlgorithm
+	}
+	signature, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return ErrMalformed
+	}
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return ErrSignature
+	}
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return ErrMalformed
+	}
+	return decodeClaims(payload, claims, expect)
+}
+
+func decodeHeader(segment string) (*header, error) {
+	data, err := b64.DecodeString(segment)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	h := &header{}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, ErrMalformed
+	}
+	return h, nil
+}
+
+func decodeClaims(payload []byte, claims interface{}, expect Expectation) error {
+	var registered Claims
+	if err := json.Unmarshal(payload, &registered); err != nil {
+		return ErrMalformed
+	}
+	if err := registered.Validate(expect); err != nil {
+		return err
+	}
+	if claims != nil {
+		if err := json.Unmarshal(payload, claims); err != nil {
+			return ErrMalformed
+		}
+	}
+	return nil
+}
+
+func randomID() string {
+	id := make([]byte, 16)
+	_, _ = io.ReadFull(rand.Reader, id)
+	return b64.EncodeToString(id)
+}
+
+// IDs returns the sorted key IDs
+func (ks *KeySet) IDs() []string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,143 @@
+package jose
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+)
+
+type adminClaims struct {
+	Claims
+	Role string `json:"role"`
+}
+
+func TestSignVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := NewHS256("hs", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := NewRS256("rs", rsaKey)
+	ed := NewEdDSA("ed", edKey)
+
+	// other services verify by the published JWKS
+	data, err := NewKeySet(hs, rs, ed).MarshalJWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(`"hs"`)) {
+		t.Errorf("HS256 key must not be exported: %s\n", data)
+	}
+	public, err := ParseJWKS(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1600000000, 0)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	for _, test := range []struct {
+		key *Key
+		ks  *KeySet
+	}{
+		{key: hs, ks: NewKeySet(hs)},
+		{key: rs, ks: public},
+		{key: ed, ks: public},
+	} {
+		claims := adminClaims{Claims: NewClaims("1", time.Hour, "admin"), Role: "root"}
+		token, err := Sign(test.key, claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got adminClaims
+		err = test.ks.Verify(token, &got, Expectation{Audience: "admin"})
+		if err != nil {
+			t.Fatalf("%s: %v", test.key.Algorithm, err)
+		}
+		if got.Subject != "1" || got.Role != "root" || got.ID == "" {
+			t.Errorf("%s: got: %+v\n", test.key.Algorithm, got)
+		}
+		if err := test.ks.Verify(token, nil, Expectation{Audience: "upload"}); err != ErrAudience {
+			t.Errorf("%s: need: %v, got: %v\n", test.key.Algorithm, ErrAudience, err)
+		}
+		parts := strings.Split(token, ".")
+		forged := parts[0] + "." + b64.EncodeToString([]byte(`{"sub":"2"}`)) + "." + parts[2]
+		if err := test.ks.Verify(forged, nil, Expectation{}); err != ErrSignature {
+			t.Errorf("%s: need: %v, got: %v\n", test.key.Algorithm, ErrSignature, err)
+		}
+		now = now.Add(time.Hour)
+		if err := test.ks.Verify(token, nil, Expectation{}); err != ErrExpired {
+			t.Errorf("%s: need: %v, got: %v\n", test.key.Algorithm, ErrExpired, err)
+		}
+		now = now.Add(-time.Hour)
+	}
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewKeySet(NewRS256Public("rs", &rsaKey.PublicKey))
+	// an HS256 token signed by the public key bytes must not be accepted by the RSA key
+	forger, err := NewHS256("rs", rsaKey.PublicKey.N.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := Sign(forger, NewClaims("1", time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Verify(token, nil, Expectation{}); err != ErrAlgorithm {
+		t.Errorf("need: %v, got: %v\n", ErrAlgorithm, err)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	token, err := Encrypt(key, "k1", NewClaims("1", time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid, err := KeyID(token); err != nil || kid != "k1" {
+		t.Errorf("need: k1, got: %s %v\n", kid, err)
+	}
+	var claims Claims
+	if err := Decrypt(key, token, &claims, Expectation{}); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "1" {
+		t.Errorf("need: 1, got: %s\n", claims.Subject)
+	}
+	if err := Decrypt(bytes.Repeat([]byte("x"), 32), token, nil, Expectation{}); err != ErrDecryption {
+		t.Errorf("need: %v, got: %v\n", ErrDecryption, err)
+	}
+}
+
+func TestHS256EmptySecret(t *testing.T) {
+	if _, err := NewHS256("hs", []byte("short")); err != ErrWeakSecret {
+		t.Errorf("need: %v, got: %v\n", ErrWeakSecret, err)
+	}
+	// a token signed by an empty HMAC key must not be accepted by a key without secret
+	mac := hmac.New(sha256.New, nil)
+	input := b64.EncodeToString([]byte(`{"alg":"HS256","kid":"hs"}`)) + "." + b64.EncodeToString([]byte(`{"sub":"1"}`))
+	mac.Write([]byte(input))
+	token := input + "." + b64.EncodeToString(mac.Sum(nil))
+	ks := NewKeySet(&Key{ID: "hs", Algorithm: HS256})
+	if err := ks.Verify(token, nil, Expectation{}); err != ErrSignature {
+		t.Errorf("need: %v, got: %v\n", ErrSignature, err)
+	}
+}
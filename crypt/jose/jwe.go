@@ -0,0 +1,104 @@
+package jose
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// JWE algorithms
+const (
+	Dir     = "dir"
+	A256GCM = "A256GCM"
+)
+
+// Encrypt returns the JWE compact serialization of claims encrypted by the 32 bytes key with direct
+// key agreement and A256GCM, kid identifies the key and may be empty
+func Encrypt(key []byte, kid string, claims interface{}) (string, error) {
+	aead, err := newA256GCM(key)
+	if err != nil {
+		return "", err
+	}
+	h, err := json.Marshal(header{Algorithm: Dir, Encryption: A256GCM, KeyID: kid, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	protected := b64.EncodeToString(h)
+	iv := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nil, iv, payload, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+	// the encrypted key is empty for direct key agreement
+	return strings.Join([]string{
+		protected,
+		"",
+		b64.EncodeToString(iv),
+		b64.EncodeToString(ciphertext),
+		b64.EncodeToString(tag),
+	}, "."), nil
+}
+
+// Decrypt decrypts the JWE token by key, decodes its payload into claims and validates the registered
+// claims
+func Decrypt(key []byte, token string, claims interface{}, expect Expectation) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 || parts[1] != "" {
+		return ErrMalformed
+	}
+	h, err := decodeHeader(parts[0])
+	if err != nil {
+		return err
+	}
+	if h.Algorithm != Dir || h.Encryption != A256GCM {
+		return ErrUnsupported
+	}
+	aead, err := newA256GCM(key)
+	if err != nil {
+		return err
+	}
+	iv, err1 := b64.DecodeString(parts[2])
+	ciphertext, err2 := b64.DecodeString(parts[3])
+	tag, err3 := b64.DecodeString(parts[4])
+	if err1 != nil || err2 != nil || err3 != nil || len(iv) != aead.NonceSize() || len(tag) != aead.Overhead() {
+		return ErrMalformed
+	}
+	payload, err := aead.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return ErrDecryption
+	}
+	return decodeClaims(payload, claims, expect)
+}
+
+// KeyID returns the "kid" header of a JWS or JWE token without verifying it, it is used to select the
+// key of Decrypt
+func KeyID(token string) (string, error) {
+	idx := strings.IndexByte(token, '.')
+	if idx < 0 {
+		return "", ErrMalformed
+	}
+	h, err := decodeHeader(token[:idx])
+	if err != nil {
+		return "", err
+	}
+	return h.KeyID, nil
+}
+
+func newA256GCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
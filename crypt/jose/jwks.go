@@ -0,0 +1,92 @@
+package jose
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"math/big"
+)
+
+// JWK is a JSON Web Key of a public key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of ks, HS256 keys are secret and never exported
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, id := range ks.IDs() {
+		key, _ := ks.Lookup(id)
+		switch key.Algorithm {
+		case RS256:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: RS256,
+				N:         b64.EncodeToString(key.rsaPublic.N.Bytes()),
+				E:         b64.EncodeToString(big.NewInt(int64(key.rsaPublic.E)).Bytes()),
+			})
+		case EdDSA:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: EdDSA,
+				Curve:     "Ed25519",
+				X:         b64.EncodeToString(key.edPublic),
+			})
+		}
+	}
+	return set
+}
+
+// MarshalJWKS returns the JSON of the public keys of ks, it is usually served at
+// "/.well-known/jwks.json"
+func (ks *KeySet) MarshalJWKS() ([]byte, error) {
+	return json.Marshal(ks.JWKS())
+}
+
+// ParseJWKS returns the key set of the RSA and Ed25519 public keys in data, other keys are skipped
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	ks := NewKeySet()
+	for _, jwk := range set.Keys {
+		switch {
+		case jwk.KeyType == "RSA":
+			n, err1 := b64.DecodeString(jwk.N)
+			e, err2 := b64.DecodeString(jwk.E)
+			if err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+				return nil, ErrInvalidKey
+			}
+			ks.Add(NewRS256Public(jwk.KeyID, &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}))
+		case jwk.KeyType == "OKP" && jwk.Curve == "Ed25519":
+			x, err := b64.DecodeString(jwk.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				return nil, ErrInvalidKey
+			}
+			ks.Add(NewEdDSAPublic(jwk.KeyID, x))
+		}
+	}
+	return ks, nil
+}
@@ -0,0 +1,164 @@
+// Package kdf derives keys from a single master secret, HKDF-SHA256 is used for high-entropy secrets
+// and Argon2id with a stored salt for passphrases. Every purpose, such as token, cookie or upload-link,
+// gets an independent sub key, so a leaked sub key does not reveal the master secret or other keys:
+//
+//	master, err := kdf.NewMasterKey("kdf", configs)
+//	tokenKey, err := master.Key(kdf.PurposeToken, 32)
+//	cookieKey, err := master.Key(kdf.PurposeCookie, 32)
+package kdf
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/morgine/pkg/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+// Purposes of the sub keys
+const (
+	PurposeToken      = "token"
+	PurposeCookie     = "cookie"
+	PurposeUploadLink = "upload-link"
+)
+
+// Modes of the master secret
+const (
+	ModeHKDF     = "hkdf"
+	ModeArgon2id = "argon2id"
+)
+
+// SaltSize is the size of the salts generated by NewSalt
+const SaltSize = 16
+
+var (
+	ErrWeakSecret   = errors.New("kdf: secret must be at least 16 bytes, use argon2id mode for passphrases")
+	ErrMissingSalt  = errors.New("kdf: argon2id mode needs a salt of at least 8 bytes")
+	ErrEmptyPurpose = errors.New("kdf: purpose must not be empty")
+	ErrKeySize      = errors.New("kdf: key size must be between 1 and 8160 bytes")
+	ErrArgon2Params = errors.New("kdf: argon2id needs time >= 1, threads >= 1 and memory >= 8 * threads KiB")
+)
+
+// maxKeySize is the maximum output size of HKDF-SHA256
+const maxKeySize = 255 * sha256.Size
+
+// HKDF derives size bytes from the high-entropy secret by HKDF-SHA256, size must not exceed 8160
+func HKDF(secret, salt []byte, info string, size int) []byte {
+	key := make([]byte, size)
+	// the reader only fails if size exceeds 255 * 32 bytes
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// Argon2Params are the cost parameters of Argon2id
+type Argon2Params struct {
+	Time    uint32 // iterations
+	Memory  uint32 // memory in KiB
+	Threads uint8
+}
+
+// Validate returns ErrArgon2Params if Argon2id panics on the parameters
+func (p Argon2Params) Validate() error {
+	if p.Time < 1 || p.Threads < 1 || p.Memory < 8*uint32(p.Threads) {
+		return ErrArgon2Params
+	}
+	return nil
+}
+
+// DefaultArgon2Params are the parameters recommended by RFC 9106 for memory constrained environments
+var DefaultArgon2Params = Argon2Params{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// Argon2id derives size bytes from passphrase and salt, the salt must be stored to derive the same key,
+// params must pass Validate
+func Argon2id(passphrase string, salt []byte, params Argon2Params, size int) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, params.Time, params.Memory, params.Threads, uint32(size))
+}
+
+// NewSalt returns SaltSize random bytes
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// MasterKey derives the sub keys of purposes
+type MasterKey struct {
+	prk []byte
+}
+
+// NewMasterKey 由 namespace 下的配置创建主密钥
+func NewMasterKey(namespace string, configs config.Configs) (*MasterKey, error) {
+	cfg := &Config{}
+	err := configs.UnmarshalSub(namespace, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.New()
+}
+
+// FromSecret returns the master key of a high-entropy secret, such as 32 random bytes
+func FromSecret(secret []byte) (*MasterKey, error) {
+	if len(secret) < 16 {
+		return nil, ErrWeakSecret
+	}
+	return &MasterKey{prk: HKDF(secret, nil, "kdf master", 32)}, nil
+}
+
+// FromPassphrase returns the master key of a passphrase stretched by Argon2id
+func FromPassphrase(passphrase string, salt []byte, params Argon2Params) (*MasterKey, error) {
+	if len(salt) < 8 {
+		return nil, ErrMissingSalt
+	}
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return &MasterKey{prk: Argon2id(passphrase, salt, params, 32)}, nil
+}
+
+// Key derives the sub key of purpose, the same purpose always derives the same key
+func (m *MasterKey) Key(purpose string, size int) ([]byte, error) {
+	if purpose == "" {
+		return nil, ErrEmptyPurpose
+	}
+	if size < 1 || size > maxKeySize {
+		return nil, ErrKeySize
+	}
+	return HKDF(m.prk, nil, "kdf purpose "+purpose, size), nil
+}
+
+// Config 密钥派生配置
+type Config struct {
+	Mode          string `toml:"mode" default:"hkdf" desc:"主密钥模式, hkdf: 高熵密钥, argon2id: 口令" validate:"oneof=hkdf argon2id"`
+	Secret        string `toml:"secret" desc:"主密钥或口令, hkdf 模式下至少 16 字节" secret:"true"`
+	Salt          string `toml:"salt" desc:"argon2id 模式的盐, base64 编码, 可由 kdf.NewSalt 生成, 修改后派生的密钥全部改变"`
+	Argon2Time    uint32 `toml:"argon2_time" default:"3" desc:"argon2id 迭代次数" validate:"min=1"`
+	Argon2Memory  uint32 `toml:"argon2_memory" default:"65536" desc:"argon2id 内存(单位: KiB)" validate:"min=8"`
+	Argon2Threads uint8  `toml:"argon2_threads" default:"4" desc:"argon2id 并行度" validate:"min=1"`
+}
+
+// New 由配置创建主密钥
+func (e Config) New() (*MasterKey, error) {
+	switch e.Mode {
+	case ModeHKDF:
+		return FromSecret([]byte(e.Secret))
+	case ModeArgon2id:
+		if e.Secret == "" {
+			return nil, errors.New("kdf: passphrase is empty")
+		}
+		salt, err := base64.StdEncoding.DecodeString(e.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("kdf: decode salt: %w", err)
+		}
+		return FromPassphrase(e.Secret, salt, Argon2Params{Time: e.Argon2Time, Memory: e.Argon2Memory, Threads: e.Argon2Threads})
+	default:
+		return nil, fmt.Errorf("kdf: unsupported mode %s", e.Mode)
+	}
+}
@@ -0,0 +1,72 @@
+package kdf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/morgine/pkg/config"
+	"testing"
+)
+
+func TestMasterKey(t *testing.T) {
+	salt := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	cs, err := config.UnmarshalMemory([]byte(`
+[kdf]
+secret = "0123456789abcdef0123456789abcdef"
+
+[passphrase]
+mode = "argon2id"
+secret = "correct horse"
+salt = "` + salt + `"
+argon2_memory = 64
+argon2_time = 1
+argon2_threads = 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, namespace := range []string{"kdf", "passphrase"} {
+		m1, err := NewMasterKey(namespace, cs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m2, _ := NewMasterKey(namespace, cs)
+		token := mustKey(t, m1, PurposeToken)
+		if len(token) != 32 || !bytes.Equal(token, mustKey(t, m2, PurposeToken)) {
+			t.Errorf("%s: need the same key of the same purpose\n", namespace)
+		}
+		if bytes.Equal(token, mustKey(t, m1, PurposeCookie)) || bytes.Equal(token, mustKey(t, m1, PurposeUploadLink)) {
+			t.Errorf("%s: need different keys of purposes\n", namespace)
+		}
+	}
+
+	if _, err := FromSecret([]byte("short")); err != ErrWeakSecret {
+		t.Errorf("need: %v, got: %v\n", ErrWeakSecret, err)
+	}
+	if _, err := (Config{Mode: ModeArgon2id, Secret: "correct horse"}).New(); err != ErrMissingSalt {
+		t.Errorf("need: %v, got: %v\n", ErrMissingSalt, err)
+	}
+	for _, params := range []Argon2Params{{}, {Time: 1, Memory: 64}, {Time: 1, Memory: 15, Threads: 2}} {
+		if _, err := FromPassphrase("correct horse", []byte("0123456789abcdef"), params); err != ErrArgon2Params {
+			t.Errorf("%+v need: %v, got: %v\n", params, ErrArgon2Params, err)
+		}
+	}
+	master, err := FromSecret([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := master.Key("", 32); err != ErrEmptyPurpose {
+		t.Errorf("need: %v, got: %v\n", ErrEmptyPurpose, err)
+	}
+	if _, err := master.Key(PurposeToken, maxKeySize+1); err != ErrKeySize {
+		t.Errorf("need: %v, got: %v\n", ErrKeySize, err)
+	}
+}
+
+func mustKey(t *testing.T, m *MasterKey, purpose string) []byte {
+	t.Helper()
+	key, err := m.Key(purpose, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -0,0 +1,77 @@
+package keyring
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/morgine/pkg/config"
+	"github.com/morgine/pkg/crypt/aead"
+	"strings"
+)
+
+// Config 密钥环配置, 如:
+//
+//	[keyring]
+//	active = "k2"
+//	algorithm = "aes-gcm"
+//	keys = ["k1=<base64 密钥>", "env:KEYRING_K2"]
+//
+// "env:" 等引用解析后的值必须为完整的 "<密钥 ID>=<base64 编码的密钥>", 如环境变量 KEYRING_K2 的值为
+// "k2=<base64 密钥>", 仅设置 base64 密钥会导致创建失败
+type Config struct {
+	Active    string   `toml:"active" desc:"当前加密使用的密钥 ID, 为空时使用第一个密钥"`
+	Algorithm string   `toml:"algorithm" default:"aes-gcm" desc:"加密算法" validate:"oneof=aes-gcm xchacha20-poly1305"`
+	Keys      []string `toml:"keys" desc:"密钥列表, 格式为 \"<密钥 ID>=<base64 编码的密钥>\"" secret:"true"`
+}
+
+// algorithms 配置中的算法名称
+var algorithms = map[string]aead.Algorithm{
+	"aes-gcm":            aead.AESGCM,
+	"xchacha20-poly1305": aead.XChaCha20Poly1305,
+}
+
+// New 由配置创建密钥环
+func (e Config) New() (*KeyRing, error) {
+	alg, ok := algorithms[e.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("keyring: unsupported algorithm %s", e.Algorithm)
+	}
+	r := New()
+	for _, item := range e.Keys {
+		idx := strings.Index(item, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("keyring: key must be \"<id>=<base64 key>\"")
+		}
+		id := item[:idx]
+		key, err := base64.StdEncoding.DecodeString(item[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("keyring: decode key %s: %w", id, err)
+		}
+		err = r.Add(id, alg, key)
+		if err != nil {
+			return nil, fmt.Errorf("keyring: add key %s: %w", id, err)
+		}
+	}
+	if e.Active != "" {
+		r.mu.Lock()
+		_, ok := r.keys[e.Active]
+		r.active = e.Active
+		r.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("keyring: active key %s: %w", e.Active, ErrUnknownKey)
+		}
+	}
+	if r.Active() == "" {
+		return nil, ErrNoActiveKey
+	}
+	return r, nil
+}
+
+// NewKeyRing 由 namespace 下的配置创建密钥环
+func NewKeyRing(namespace string, configs config.Configs) (*KeyRing, error) {
+	cfg := &Config{}
+	err := configs.UnmarshalSub(namespace, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.New()
+}
@@ -0,0 +1,239 @@
+// Package keyring encrypts with the active key of a key ring and decrypts with any known key, so keys
+// can be rotated without losing the values encrypted by the previous keys. The key ID is embedded in
+// the ciphertext header and authenticated together with the associated data:
+//
+//	+---------+---------------+--------+---------------------------------+
+//	| version | key ID length | key ID | aead envelope                   |
+//	| 1 byte  | 1 byte        |        | version, algorithm, nonce, data |
+//	+---------+---------------+--------+---------------------------------+
+package keyring
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/morgine/pkg/crypt/aead"
+	"sort"
+	"sync"
+)
+
+// Version is the header version written by Seal
+const Version byte = 1
+
+var (
+	ErrNoActiveKey        = errors.New("keyring: no active key")
+	ErrUnknownKey         = errors.New("keyring: unknown key ID")
+	ErrInvalidKeyID       = errors.New("keyring: key ID must be 1 to 255 bytes")
+	ErrDuplicateKey       = errors.New("keyring: duplicate key ID")
+	ErrCiphertextTooShort = errors.New("keyring: ciphertext too short")
+	ErrUnsupportedVersion = errors.New("keyring: unsupported version")
+)
+
+// RotateHook is called after the active key changed, it should re-encrypt the stored values by
+// Reencrypt, values encrypted by the old key can still be decrypted until the old key is removed
+type RotateHook func(r *KeyRing, oldID, newID string) error
+
+// KeyRing holds multiple keys identified by key ID, it is safe for concurrent use
+type KeyRing struct {
+	mu     sync.RWMutex
+	keys   map[string]*aead.Cipher
+	active string
+	hooks  []RotateHook
+}
+
+// New returns an empty key ring
+func New() *KeyRing {
+	return &KeyRing{keys: make(map[string]*aead.Cipher)}
+}
+
+// Add adds the key of id, the first added key becomes the active key, ErrDuplicateKey is returned if
+// the key of id exists, keys are never replaced so the values encrypted by them stay decryptable
+func (r *KeyRing) Add(id string, alg aead.Algorithm, key []byte) error {
+	if len(id) == 0 || len(id) > 255 {
+		return ErrInvalidKeyID
+	}
+	c, err := aead.NewCipher(alg, key)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[id]; ok {
+		return ErrDuplicateKey
+	}
+	r.keys[id] = c
+	if r.active == "" {
+		r.active = id
+	}
+	return nil
+}
+
+// Remove removes the key of id, values encrypted by the key can not be decrypted any more, the active
+// key can not be removed
+func (r *KeyRing) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == r.active {
+		return fmt.Errorf("keyring: can not remove the active key %s", id)
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+// IDs returns the sorted IDs of the known keys
+func (r *KeyRing) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.keys))
+	for id := range r.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Has reports whether the key of id is known
+func (r *KeyRing) Has(id string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.keys[id]
+	return ok
+}
+
+// Active returns the ID of the active key
+func (r *KeyRing) Active() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active
+}
+
+// OnRotate adds a hook which is called by SetActive and Rotate
+func (r *KeyRing) OnRotate(hook RotateHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, hook)
+}
+
+// SetActive activates the known key of id and calls the rotate hooks, the first hook error is returned
+// after all hooks are called
+func (r *KeyRing) SetActive(id string) error {
+	r.mu.Lock()
+	if _, ok := r.keys[id]; !ok {
+		r.mu.Unlock()
+		return ErrUnknownKey
+	}
+	old := r.active
+	r.active = id
+	hooks := append([]RotateHook(nil), r.hooks...)
+	r.mu.Unlock()
+	if old == id {
+		return nil
+	}
+	var first error
+	for _, hook := range hooks {
+		if err := hook(r, old, id); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Rotate adds the key of id and activates it
+func (r *KeyRing) Rotate(id string, alg aead.Algorithm, key []byte) error {
+	err := r.Add(id, alg, key)
+	if err != nil {
+		return err
+	}
+	return r.SetActive(id)
+}
+
+// Seal encrypts plaintext by the active key
+func (r *KeyRing) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	r.mu.RLock()
+	id := r.active
+	c := r.keys[id]
+	r.mu.RUnlock()
+	if c == nil {
+		return nil, ErrNoActiveKey
+	}
+	header := append([]byte{Version, byte(len(id))}, id...)
+	envelope, err := c.Seal(plaintext, bindHeader(header, additionalData))
+	if err != nil {
+		return nil, err
+	}
+	return append(header, envelope...), nil
+}
+
+// Open decrypts ciphertext by the key of its key ID
+func (r *KeyRing) Open(ciphertext, additionalData []byte) ([]byte, error) {
+	id, header, err := parseHeader(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	c := r.keys[id]
+	r.mu.RUnlock()
+	if c == nil {
+		return nil, ErrUnknownKey
+	}
+	return c.Open(ciphertext[len(header):], bindHeader(header, additionalData))
+}
+
+// SealString is the same as Seal but returns base64url encoded string
+func (r *KeyRing) SealString(plaintext, additionalData []byte) (string, error) {
+	ciphertext, err := r.Seal(plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(ciphertext), nil
+}
+
+// OpenString opens the base64url encoded ciphertext
+func (r *KeyRing) OpenString(data string, additionalData []byte) ([]byte, error) {
+	ciphertext, err := base64.URLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.Open(ciphertext, additionalData)
+}
+
+// NeedsReencrypt reports whether ciphertext is not encrypted by the active key
+func (r *KeyRing) NeedsReencrypt(ciphertext []byte) bool {
+	id, _, err := parseHeader(ciphertext)
+	return err != nil || id != r.Active()
+}
+
+// Reencrypt decrypts ciphertext and encrypts it by the active key, it is used by the rotate hooks
+func (r *KeyRing) Reencrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	plaintext, err := r.Open(ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return r.Seal(plaintext, additionalData)
+}
+
+// KeyID returns the key ID of ciphertext
+func KeyID(ciphertext []byte) (string, error) {
+	id, _, err := parseHeader(ciphertext)
+	return id, err
+}
+
+func parseHeader(ciphertext []byte) (id string, header []byte, err error) {
+	if len(ciphertext) < 2 {
+		return "", nil, ErrCiphertextTooShort
+	}
+	if ciphertext[0] != Version {
+		return "", nil, ErrUnsupportedVersion
+	}
+	size := 2 + int(ciphertext[1])
+	if len(ciphertext) < size {
+		return "", nil, ErrCiphertextTooShort
+	}
+	return string(ciphertext[2:size]), ciphertext[:size], nil
+}
+
+// bindHeader authenticates the header together with the associated data
+func bindHeader(header, additionalData []byte) []byte {
+	ad := make([]byte, 0, len(header)+len(additionalData))
+	return append(append(ad, header...), additionalData...)
+}
@@ -0,0 +1,113 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"github.com/morgine/pkg/config"
+	"github.com/morgine/pkg/crypt/aead"
+	"testing"
+)
+
+func TestKeyRingRotate(t *testing.T) {
+	r := New()
+	err := r.Add("k1", aead.AESGCM, bytes.Repeat([]byte("1"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("hello world!")
+	old, err := r.Seal(message, []byte("ad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := [][]byte{old}
+	r.OnRotate(func(r *KeyRing, oldID, newID string) error {
+		for i, value := range stored {
+			if r.NeedsReencrypt(value) {
+				value, err := r.Reencrypt(value, []byte("ad"))
+				if err != nil {
+					return err
+				}
+				stored[i] = value
+			}
+		}
+		return nil
+	})
+	err = r.Rotate("k2", aead.XChaCha20Poly1305, bytes.Repeat([]byte("2"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := KeyID(stored[0]); id != "k2" {
+		t.Errorf("need: k2, got: %s\n", id)
+	}
+	// existing keys are never replaced
+	if err := r.Add("k1", aead.AESGCM, bytes.Repeat([]byte("3"), 32)); err != ErrDuplicateKey {
+		t.Errorf("need: %v, got: %v\n", ErrDuplicateKey, err)
+	}
+	if err := r.Rotate("k2", aead.AESGCM, bytes.Repeat([]byte("3"), 32)); err != ErrDuplicateKey {
+		t.Errorf("need: %v, got: %v\n", ErrDuplicateKey, err)
+	}
+	// values of the old key can still be decrypted
+	for _, value := range [][]byte{old, stored[0]} {
+		got, err := r.Open(value, []byte("ad"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(message, got) {
+			t.Errorf("need: %s, got: %s\n", message, got)
+		}
+	}
+	if err := r.Remove("k2"); err == nil {
+		t.Error("need error of removing the active key")
+	}
+	if err := r.Remove("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Open(old, []byte("ad")); err != ErrUnknownKey {
+		t.Errorf("need: %v, got: %v\n", ErrUnknownKey, err)
+	}
+}
+
+func TestKeyRingTamperedKeyID(t *testing.T) {
+	r := New()
+	key := bytes.Repeat([]byte("1"), 32)
+	_ = r.Add("k1", aead.AESGCM, key)
+	_ = r.Add("k2", aead.AESGCM, key)
+	ciphertext, err := r.Seal([]byte("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the key ID is authenticated even if both keys are the same
+	ciphertext[3] = '2'
+	if _, err := r.Open(ciphertext, nil); err != aead.ErrAuthentication {
+		t.Errorf("need: %v, got: %v\n", aead.ErrAuthentication, err)
+	}
+}
+
+func TestNewKeyRing(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("1"), 32))
+	k2 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("2"), 16))
+	cs, err := config.UnmarshalMemory([]byte(`
+[keyring]
+active = "k2"
+keys = ["k1=` + k1 + `", "k2=` + k2 + `"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewKeyRing("keyring", cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Active() != "k2" || len(r.IDs()) != 2 {
+		t.Errorf("need active k2 of 2 keys, got: %s of %v\n", r.Active(), r.IDs())
+	}
+	_, err = Config{Algorithm: "aes-gcm", Active: "k3", Keys: []string{"k1=" + k1}}.New()
+	if err == nil {
+		t.Error("need error of unknown active key")
+	}
+	_, err = Config{Algorithm: "aes-gcm", Keys: []string{"k1=" + k1, "k1=" + k2}}.New()
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("need: %v, got: %v\n", ErrDuplicateKey, err)
+	}
+}
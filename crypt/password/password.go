@@ -0,0 +1,363 @@
+// Package password hashes passwords with Argon2id, scrypt or bcrypt, hashes are encoded as PHC
+// strings so the algorithm and parameters are stored with the hash:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+//	$scrypt$ln=15,r=8,p=1$<salt>$<hash>
+//	$2a$10$<salt and hash>                  (bcrypt)
+//
+// salts and hashes are base64 encoded without padding. Hashes of any supported algorithm can be
+// verified, NeedsRehash reports whether a hash should be upgraded to the configured algorithm and
+// parameters, which is usually done after a successful login.
+package password
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/morgine/pkg/config"
+	"github.com/morgine/pkg/crypt/kdf"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Algorithms
+const (
+	Argon2id = "argon2id"
+	Scrypt   = "scrypt"
+	Bcrypt   = "bcrypt"
+)
+
+var (
+	ErrInvalidHash = errors.New("password: invalid hash")
+	ErrTooShort    = errors.New("password: too short")
+	ErrBreached    = errors.New("password: found in breached password list")
+	ErrScrypt      = errors.New("password: scrypt needs 1 <= ln <= 30, r >= 1, p >= 1 and r * p < 2^30")
+	ErrBcryptCost  = fmt.Errorf("password: bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+)
+
+const (
+	saltSize = 16
+	keySize  = 32
+)
+
+// Config 密码哈希配置
+type Config struct {
+	Algorithm     string `toml:"algorithm" default:"argon2id" desc:"哈希算法" validate:"oneof=argon2id scrypt bcrypt"`
+	Argon2Memory  uint32 `toml:"argon2_memory" default:"65536" desc:"argon2id 内存(单位: KiB)" validate:"min=8"`
+	Argon2Time    uint32 `toml:"argon2_time" default:"3" desc:"argon2id 迭代次数" validate:"min=1"`
+	Argon2Threads uint8  `toml:"argon2_threads" default:"2" desc:"argon2id 并行度" validate:"min=1"`
+	ScryptLogN    int    `toml:"scrypt_ln" default:"15" desc:"scrypt CPU/内存开销, N = 2^ln" validate:"min=1,max=30"`
+	ScryptR       int    `toml:"scrypt_r" default:"8" desc:"scrypt 块大小" validate:"min=1"`
+	ScryptP       int    `toml:"scrypt_p" default:"1" desc:"scrypt 并行度" validate:"min=1"`
+	BcryptCost    int    `toml:"bcrypt_cost" default:"10" desc:"bcrypt 开销" validate:"min=4,max=31"`
+	MinLength     int    `toml:"min_length" default:"8" desc:"密码最小长度(字符数)" validate:"min=0"`
+	BreachedFile  string `toml:"breached_file" desc:"泄露密码列表文件, 每行一个密码或其 SHA-1 十六进制值(可带 \":次数\" 后缀), 为空时不检查"`
+}
+
+// Hasher hashes and verifies passwords, it is safe for concurrent use
+type Hasher struct {
+	cfg      Config
+	breached map[string]bool
+}
+
+// Default is the hasher of the default config
+var Default, _ = New(DefaultConfig())
+
+// DefaultConfig returns the config of the `default` tags
+func DefaultConfig() Config {
+	cfg := Config{}
+	_ = config.ApplyDefaults(&cfg)
+	return cfg
+}
+
+// New returns the hasher of cfg, the parameters of the configured algorithm are validated, kdf.ErrArgon2Params,
+// ErrScrypt or ErrBcryptCost is returned if they are out of range. The breached password file is loaded if it is set
+func New(cfg Config) (*Hasher, error) {
+	switch cfg.Algorithm {
+	case Argon2id:
+		params := kdf.Argon2Params{Time: cfg.Argon2Time, Memory: cfg.Argon2Memory, Threads: cfg.Argon2Threads}
+		if err := params.Validate(); err != nil {
+			return nil, err
+		}
+	case Scrypt:
+		if cfg.ScryptLogN < 1 || cfg.ScryptLogN > 30 || cfg.ScryptR < 1 || cfg.ScryptP < 1 ||
+			uint64(cfg.ScryptR)*uint64(cfg.ScryptP) >= 1<<30 {
+			return nil, ErrScrypt
+		}
+	case Bcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, ErrBcryptCost
+		}
+	default:
+		return nil, fmt.Errorf("password: unsupported algorithm %s", cfg.Algorithm)
+	}
+	h := &Hasher{cfg: cfg}
+	if cfg.BreachedFile != "" {
+		breached, err := loadBreached(cfg.BreachedFile)
+		if err != nil {
+			return nil, err
+		}
+		h.breached = breached
+	}
+	return h, nil
+}
+
+// NewHasher 由 namespace 下的配置创建哈希器
+func NewHasher(namespace string, configs config.Configs) (*Hasher, error) {
+	cfg := &Config{}
+	err := configs.UnmarshalSub(namespace, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return New(*cfg)
+}
+
+// Check checks password against the policy, ErrTooShort or ErrBreached is returned if it fails
+func (h *Hasher) Check(password string) error {
+	if utf8.RuneCountInString(password) < h.cfg.MinLength {
+		return ErrTooShort
+	}
+	if h.breached != nil && h.breached[sha1Hex(password)] {
+		return ErrBreached
+	}
+	return nil
+}
+
+// Hash hashes password by the configured algorithm, the policy is not checked
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.cfg.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+	var p phc
+	if h.cfg.Algorithm == Argon2id {
+		p = phc{
+			id:      Argon2id,
+			version: argon2.Version,
+			params: map[string]int{
+				"m": int(h.cfg.Argon2Memory),
+				"t": int(h.cfg.Argon2Time),
+				"p": int(h.cfg.Argon2Threads),
+			},
+			salt: salt,
+		}
+	} else {
+		p = phc{
+			id:     Scrypt,
+			params: map[string]int{"ln": h.cfg.ScryptLogN, "r": h.cfg.ScryptR, "p": h.cfg.ScryptP},
+			salt:   salt,
+		}
+	}
+	hash, err := p.derive(password, keySize)
+	if err != nil {
+		return "", err
+	}
+	p.hash = hash
+	return p.String(), nil
+}
+
+// Verify reports whether password matches the hash of any supported algorithm, ErrInvalidHash is
+// returned if the hash is malformed
+func (h *Hasher) Verify(password, encoded string) (bool, error) {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		switch err {
+		case nil:
+			return true, nil
+		case bcrypt.ErrMismatchedHashAndPassword:
+			return false, nil
+		default:
+			return false, ErrInvalidHash
+		}
+	}
+	p, err := parsePHC(encoded)
+	if err != nil {
+		return false, err
+	}
+	hash, err := p.derive(password, len(p.hash))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(hash, p.hash) == 1, nil
+}
+
+// NeedsRehash reports whether the hash is not produced by the configured algorithm and parameters
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	if isBcrypt(encoded) {
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return h.cfg.Algorithm != Bcrypt || err != nil || cost != h.cfg.BcryptCost
+	}
+	p, err := parsePHC(encoded)
+	if err != nil || p.id != h.cfg.Algorithm {
+		return true
+	}
+	switch p.id {
+	case Argon2id:
+		return p.version != argon2.Version ||
+			p.params["m"] != int(h.cfg.Argon2Memory) ||
+			p.params["t"] != int(h.cfg.Argon2Time) ||
+			p.params["p"] != int(h.cfg.Argon2Threads)
+	default:
+		return p.params["ln"] != h.cfg.ScryptLogN || p.params["r"] != h.cfg.ScryptR || p.params["p"] != h.cfg.ScryptP
+	}
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// phc is a parsed PHC string of argon2id or scrypt
+type phc struct {
+	id      string
+	version int
+	params  map[string]int
+	salt    []byte
+	hash    []byte
+}
+
+// paramNames keeps the order of the parameters in PHC strings
+var paramNames = map[string][]string{
+	Argon2id: {"m", "t", "p"},
+	Scrypt:   {"ln", "r", "p"},
+}
+
+func (p phc) String() string {
+	buf := new(strings.Builder)
+	buf.WriteString("$" + p.id)
+	if p.id == Argon2id {
+		fmt.Fprintf(buf, "$v=%d", p.version)
+	}
+	params := make([]string, 0, len(p.params))
+	for _, name := range paramNames[p.id] {
+		params = append(params, name+"="+strconv.Itoa(p.params[name]))
+	}
+	buf.WriteString("$" + strings.Join(params, ","))
+	buf.WriteString("$" + base64.RawStdEncoding.EncodeToString(p.salt))
+	buf.WriteString("$" + base64.RawStdEncoding.EncodeToString(p.hash))
+	return buf.String()
+}
+
+func parsePHC(encoded string) (*phc, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) < 5 || parts[0] != "" {
+		return nil, ErrInvalidHash
+	}
+	p := &phc{id: parts[1], params: make(map[string]int)}
+	names, ok := paramNames[p.id]
+	if !ok {
+		return nil, ErrInvalidHash
+	}
+	parts = parts[2:]
+	if p.id == Argon2id {
+		if len(parts) != 4 || !strings.HasPrefix(parts[0], "v=") {
+			return nil, ErrInvalidHash
+		}
+		version, err := strconv.Atoi(parts[0][2:])
+		if err != nil {
+			return nil, ErrInvalidHash
+		}
+		p.version = version
+		parts = parts[1:]
+	}
+	if len(parts) != 3 {
+		return nil, ErrInvalidHash
+	}
+	for _, param := range strings.Split(parts[0], ",") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return nil, ErrInvalidHash
+		}
+		value, err := strconv.Atoi(kv[1])
+		if err != nil || value <= 0 {
+			return nil, ErrInvalidHash
+		}
+		p.params[kv[0]] = value
+	}
+	for _, name := range names {
+		if _, ok := p.params[name]; !ok {
+			return nil, ErrInvalidHash
+		}
+	}
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[1]); err != nil {
+		return nil, ErrInvalidHash
+	}
+	if p.hash, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil || len(p.hash) == 0 {
+		return nil, ErrInvalidHash
+	}
+	return p, nil
+}
+
+// derive derives the key of password by the parameters of p
+func (p *phc) derive(password string, size int) ([]byte, error) {
+	switch p.id {
+	case Argon2id:
+		if p.version != argon2.Version || p.params["p"] > 255 {
+			return nil, ErrInvalidHash
+		}
+		return argon2.IDKey([]byte(password), p.salt, uint32(p.params["t"]), uint32(p.params["m"]),
+			uint8(p.params["p"]), uint32(size)), nil
+	default:
+		if p.params["ln"] > 30 {
+			return nil, ErrInvalidHash
+		}
+		key, err := scrypt.Key([]byte(password), p.salt, 1<<uint(p.params["ln"]), p.params["r"], p.params["p"], size)
+		if err != nil {
+			return nil, ErrInvalidHash
+		}
+		return key, nil
+	}
+}
+
+// loadBreached loads the SHA-1 hex values of the breached passwords, a line is either the SHA-1 hex
+// value with an optional ":count" suffix, such as the lists of Have I Been Pwned, or a plain password
+func loadBreached(filename string) (map[string]bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	breached := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		hash := line
+		if idx := strings.IndexByte(hash, ':'); idx >= 0 {
+			hash = hash[:idx]
+		}
+		if len(hash) == sha1.Size*2 {
+			if _, err := hex.DecodeString(hash); err == nil {
+				breached[strings.ToUpper(hash)] = true
+				continue
+			}
+		}
+		breached[sha1Hex(line)] = true
+	}
+	return breached, scanner.Err()
+}
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,129 @@
+package password
+
+import (
+	"github.com/morgine/pkg/crypt/kdf"
+	"golang.org/x/crypto/bcrypt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testConfig(alg string) Config {
+	cfg := DefaultConfig()
+	cfg.Algorithm = alg
+	cfg.Argon2Memory, cfg.Argon2Time, cfg.Argon2Threads = 64, 1, 1
+	cfg.ScryptLogN = 4
+	cfg.BcryptCost = bcrypt.MinCost
+	return cfg
+}
+
+func TestHashVerify(t *testing.T) {
+	for _, alg := range []string{Argon2id, Scrypt, Bcrypt} {
+		h, err := New(testConfig(alg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := h.Hash("correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alg != Bcrypt && !strings.HasPrefix(hash, "$"+alg+"$") {
+			t.Errorf("%s: need PHC string, got: %s\n", alg, hash)
+		}
+		ok, err := h.Verify("correct horse", hash)
+		if err != nil || !ok {
+			t.Errorf("%s: need match, got: %v %v\n", alg, ok, err)
+		}
+		ok, err = h.Verify("wrong horse", hash)
+		if err != nil || ok {
+			t.Errorf("%s: need mismatch, got: %v %v\n", alg, ok, err)
+		}
+		if h.NeedsRehash(hash) {
+			t.Errorf("%s: need no rehash\n", alg)
+		}
+	}
+	if _, err := Default.Verify("password", "$argon2id$v=19$m=64$c2FsdA$aGFzaA"); err != ErrInvalidHash {
+		t.Errorf("need: %v, got: %v\n", ErrInvalidHash, err)
+	}
+}
+
+func TestNewInvalidParams(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("need error of zero value config")
+	}
+	tests := map[string]error{
+		Argon2id: kdf.ErrArgon2Params,
+		Scrypt:   ErrScrypt,
+		Bcrypt:   ErrBcryptCost,
+	}
+	for alg, need := range tests {
+		if _, err := New(Config{Algorithm: alg}); err != need {
+			t.Errorf("%s: need: %v, got: %v\n", alg, need, err)
+		}
+	}
+	cfg := testConfig(Scrypt)
+	cfg.ScryptR, cfg.ScryptP = 1<<15, 1<<15
+	if _, err := New(cfg); err != ErrScrypt {
+		t.Errorf("need: %v, got: %v\n", ErrScrypt, err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	old, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := New(testConfig(Argon2id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// old bcrypt hashes are verified and upgraded
+	ok, err := h.Verify("correct horse", string(old))
+	if err != nil || !ok {
+		t.Fatalf("need match, got: %v %v\n", ok, err)
+	}
+	if !h.NeedsRehash(string(old)) {
+		t.Error("need rehash of bcrypt hash")
+	}
+	hash, _ := h.Hash("correct horse")
+	cfg := testConfig(Argon2id)
+	cfg.Argon2Time = 2
+	stronger, _ := New(cfg)
+	if !stronger.NeedsRehash(hash) {
+		t.Error("need rehash of weaker parameters")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "breached.txt")
+	// sha1("password1234") with count and a plain password
+	err = ioutil.WriteFile(filename, []byte("E6B6AFBD6D76BB5D2041542D7D2E3FAC5BB05593:42\nletmein123\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(Argon2id)
+	cfg.BreachedFile = filename
+	h, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]error{
+		"short":         ErrTooShort,
+		"password1234":  ErrBreached,
+		"letmein123":    ErrBreached,
+		"correct horse": nil,
+		"密码密码密码密码":      nil,
+	}
+	for pwd, need := range tests {
+		if got := h.Check(pwd); got != need {
+			t.Errorf("%s: need: %v, got: %v\n", pwd, need, got)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// Package sign signs values to tamper-evident tokens, such as signed download links, state parameters
+// and cookies. A token is the base64url encoding, the same encoding as aes.AesCBCEncrypt, of:
+//
+//	+---------+-----------+---------------+--------+------------+-------+-----------+
+//	| version | algorithm | key ID length | key ID | expires at | value | signature |
+//	| 1 byte  | 1 byte    | 1 byte        |        | 8 bytes    |       |           |
+//	+---------+-----------+---------------+--------+------------+-------+-----------+
+//
+// expires at is the big-endian unix seconds, 0 if the token never expires. The signature covers the
+// purpose too, so a token signed for one purpose can not be used for another one.
+package sign
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Now returns the current time, it is replaced by tests
+var Now = time.Now
+
+// Version is the token version written by Sign
+const Version byte = 1
+
+// Algorithm is the signature algorithm of a key
+type Algorithm byte
+
+const (
+	HS256 Algorithm = 1 // HMAC-SHA256
+	EdDSA Algorithm = 2 // Ed25519
+)
+
+var (
+	ErrInvalidToken = errors.New("sign: invalid token")
+	ErrBadSignature = errors.New("sign: bad signature")
+	ErrExpired      = errors.New("sign: token expired")
+	ErrUnknownKey   = errors.New("sign: unknown key ID")
+	ErrNoActiveKey  = errors.New("sign: no active key")
+	ErrVerifyOnly   = errors.New("sign: key can only verify")
+	ErrInvalidKey   = errors.New("sign: invalid key")
+	ErrInvalidKeyID = errors.New("sign: key ID must be 1 to 255 bytes")
+)
+
+type key struct {
+	alg     Algorithm
+	secret  []byte
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+func (k *key) sign(message []byte) ([]byte, error) {
+	switch k.alg {
+	case HS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(message)
+		return mac.Sum(nil), nil
+	default:
+		if k.private == nil {
+			return nil, ErrVerifyOnly
+		}
+		return ed25519.Sign(k.private, message), nil
+	}
+}
+
+func (k *key) verify(message, signature []byte) bool {
+	switch k.alg {
+	case HS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(message)
+		return subtle.ConstantTimeCompare(mac.Sum(nil), signature) == 1
+	default:
+		return ed25519.Verify(k.public, message, signature)
+	}
+}
+
+func signatureSize(alg Algorithm) int {
+	switch alg {
+	case HS256:
+		return sha256.Size
+	case EdDSA:
+		return ed25519.SignatureSize
+	default:
+		return -1
+	}
+}
+
+// Signer signs with the active key and verifies with any known key, it is safe for concurrent use
+type Signer struct {
+	mu     sync.RWMutex
+	keys   map[string]*key
+	active string
+}
+
+// New returns a signer without keys
+func New() *Signer {
+	return &Signer{keys: make(map[string]*key)}
+}
+
+// AddHMAC adds the HMAC-SHA256 secret of id, the secret should be at least 32 bytes
+func (s *Signer) AddHMAC(id string, secret []byte) error {
+	if len(secret) == 0 {
+		return ErrInvalidKey
+	}
+	return s.add(id, &key{alg: HS256, secret: secret})
+}
+
+// AddEd25519 adds the Ed25519 private key of id
+func (s *Signer) AddEd25519(id string, private ed25519.PrivateKey) error {
+	if len(private) != ed25519.PrivateKeySize {
+		return ErrInvalidKey
+	}
+	return s.add(id, &key{alg: EdDSA, private: private, public: private.Public().(ed25519.PublicKey)})
+}
+
+// AddEd25519Public adds the Ed25519 public key of id, which can only verify tokens signed by other
+// services
+func (s *Signer) AddEd25519Public(id string, public ed25519.PublicKey) error {
+	if len(public) != ed25519.PublicKeySize {
+		return ErrInvalidKey
+	}
+	return s.add(id, &key{alg: EdDSA, public: public})
+}
+
+// add adds the key, the first key which can sign becomes the active key
+func (s *Signer) add(id string, k *key) error {
+	if len(id) == 0 || len(id) > 255 {
+		return ErrInvalidKeyID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[id] = k
+	if s.active == "" && (k.alg == HS256 || k.private != nil) {
+		s.active = id
+	}
+	return nil
+}
+
+// SetActive signs with the key of id
+func (s *Signer) SetActive(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return ErrUnknownKey
+	}
+	if k.alg == EdDSA && k.private == nil {
+		return ErrVerifyOnly
+	}
+	s.active = id
+	return nil
+}
+
+// Remove removes the key of id, tokens signed by it can not be verified any more
+func (s *Signer) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, id)
+	if s.active == id {
+		s.active = ""
+	}
+}
+
+// IDs returns the sorted IDs of the known keys
+func (s *Signer) IDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// PublicKeys returns the Ed25519 public keys by key ID, they can be shared with the services which
+// verify the tokens
+func (s *Signer) PublicKeys() map[string]ed25519.PublicKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make(map[string]ed25519.PublicKey)
+	for id, k := range s.keys {
+		if k.alg == EdDSA {
+			keys[id] = k.public
+		}
+	}
+	return keys
+}
+
+// Sign signs value for purpose by the active key, the token expires after ttl, or never if ttl is 0
+func (s *Signer) Sign(purpose string, value []byte, ttl time.Duration) (string, error) {
+	s.mu.RLock()
+	id := s.active
+	k := s.keys[id]
+	s.mu.RUnlock()
+	if k == nil {
+		return "", ErrNoActiveKey
+	}
+	var expires int64
+	if ttl > 0 {
+		expires = Now().Add(ttl).Unix()
+	}
+	message := make([]byte, 0, 3+len(id)+8+len(value)+signatureSize(k.alg))
+	message = append(message, Version, byte(k.alg), byte(len(id)))
+	message = append(message, id...)
+	message = append(message, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(message[len(message)-8:], uint64(expires))
+	message = append(message, value...)
+	signature, err := k.sign(bindPurpose(purpose, message))
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(append(message, signature...)), nil
+}
+
+// Verify verifies token for purpose and returns the signed value
+func (s *Signer) Verify(purpose, token string) ([]byte, error) {
+	data, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if len(data) < 3 || data[0] != Version {
+		return nil, ErrInvalidToken
+	}
+	alg := Algorithm(data[1])
+	size := signatureSize(alg)
+	headerSize := 3 + int(data[2]) + 8
+	if size < 0 || len(data) < headerSize+size {
+		return nil, ErrInvalidToken
+	}
+	id := string(data[3 : 3+int(data[2])])
+	s.mu.RLock()
+	k := s.keys[id]
+	s.mu.RUnlock()
+	if k == nil {
+		return nil, ErrUnknownKey
+	}
+	if k.alg != alg {
+		return nil, ErrBadSignature
+	}
+	message, signature := data[:len(data)-size], data[len(data)-size:]
+	if !k.verify(bindPurpose(purpose, message), signature) {
+		return nil, ErrBadSignature
+	}
+	expires := int64(binary.BigEndian.Uint64(message[headerSize-8 : headerSize]))
+	if expires > 0 && Now().Unix() >= expires {
+		return nil, ErrExpired
+	}
+	return message[headerSize:], nil
+}
+
+// SignString is the same as Sign but signs a string value
+func (s *Signer) SignString(purpose, value string, ttl time.Duration) (string, error) {
+	return s.Sign(purpose, []byte(value), ttl)
+}
+
+// VerifyString is the same as Verify but returns a string value
+func (s *Signer) VerifyString(purpose, token string) (string, error) {
+	value, err := s.Verify(purpose, token)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// SetCookie signs the value of cookie and sets it to w, the purpose is bound to the cookie name and
+// the token expires with cookie.MaxAge if it is positive
+func (s *Signer) SetCookie(w http.ResponseWriter, cookie *http.Cookie) error {
+	var ttl time.Duration
+	if cookie.MaxAge > 0 {
+		ttl = time.Duration(cookie.MaxAge) * time.Second
+	}
+	token, err := s.SignString(cookiePurpose(cookie.Name), cookie.Value, ttl)
+	if err != nil {
+		return err
+	}
+	signed := *cookie
+	signed.Value = token
+	http.SetCookie(w, &signed)
+	return nil
+}
+
+// Cookie returns the verified value of the cookie of name
+func (s *Signer) Cookie(r *http.Request, name string) (string, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return s.VerifyString(cookiePurpose(name), cookie.Value)
+}
+
+func cookiePurpose(name string) string {
+	return "cookie:" + name
+}
+
+// bindPurpose prefixes message with the length and bytes of purpose
+func bindPurpose(purpose string, message []byte) []byte {
+	bound := make([]byte, 4, 4+len(purpose)+len(message))
+	binary.BigEndian.PutUint32(bound, uint32(len(purpose)))
+	bound = append(bound, purpose...)
+	return append(bound, message...)
+}
@@ -0,0 +1,103 @@
+package sign
+
+import (
+	"crypto/ed25519"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := New()
+	if err := hs.AddHMAC("h1", []byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	ed := New()
+	if err := ed.AddEd25519("e1", private); err != nil {
+		t.Fatal(err)
+	}
+	// the verifier of other services only knows the public key
+	verifier := New()
+	for id, public := range ed.PublicKeys() {
+		if err := verifier.AddEd25519Public(id, public); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Unix(1600000000, 0)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	for name, pair := range map[string][2]*Signer{"hmac": {hs, hs}, "ed25519": {ed, verifier}} {
+		token, err := pair[0].SignString("download", "upload/a.mp4", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := pair[1].VerifyString("download", token)
+		if err != nil || value != "upload/a.mp4" {
+			t.Errorf("%s: need: upload/a.mp4, got: %s %v\n", name, value, err)
+		}
+		if _, err := pair[1].Verify("state", token); err != ErrBadSignature {
+			t.Errorf("%s: need: %v, got: %v\n", name, ErrBadSignature, err)
+		}
+		tampered := []byte(token)
+		tampered[len(tampered)/2] ^= 1
+		if _, err := pair[1].Verify("download", string(tampered)); err == nil {
+			t.Errorf("%s: need error of tampered token\n", name)
+		}
+		now = now.Add(time.Minute)
+		if _, err := pair[1].Verify("download", token); err != ErrExpired {
+			t.Errorf("%s: need: %v, got: %v\n", name, ErrExpired, err)
+		}
+		now = now.Add(-time.Minute)
+	}
+	if _, err := verifier.Sign("download", nil, 0); err != ErrNoActiveKey {
+		t.Errorf("need: %v, got: %v\n", ErrNoActiveKey, err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	s := New()
+	_ = s.AddHMAC("k1", []byte("secret 1"))
+	old, err := s.SignString("state", "abc", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.AddHMAC("k2", []byte("secret 2"))
+	if err := s.SetActive("k2"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := s.VerifyString("state", old); err != nil || value != "abc" {
+		t.Errorf("need: abc, got: %s %v\n", value, err)
+	}
+	s.Remove("k1")
+	if _, err := s.Verify("state", old); err != ErrUnknownKey {
+		t.Errorf("need: %v, got: %v\n", ErrUnknownKey, err)
+	}
+}
+
+func TestCookie(t *testing.T) {
+	s := New()
+	_ = s.AddHMAC("k1", []byte("secret"))
+	w := httptest.NewRecorder()
+	err := s.SetCookie(w, &http.Cookie{Name: "uid", Value: "10", MaxAge: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+		// a token of another cookie is rejected
+		r.AddCookie(&http.Cookie{Name: "admin", Value: c.Value})
+	}
+	if value, err := s.Cookie(r, "uid"); err != nil || value != "10" {
+		t.Errorf("need: 10, got: %s %v\n", value, err)
+	}
+	if _, err := s.Cookie(r, "admin"); err != ErrBadSignature {
+		t.Errorf("need: %v, got: %v\n", ErrBadSignature, err)
+	}
+}
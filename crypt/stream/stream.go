@@ -0,0 +1,377 @@
+// Package stream encrypts large payloads in chunks with the STREAM construction, so files can be
+// encrypted and decrypted without holding them in memory, and decrypted at random offsets for range
+// reads. The format is:
+//
+//	+---------+------------+--------+--------------+---------+-----+------------+
+//	| version | chunk size | salt   | nonce prefix | chunk 0 | ... | last chunk |
+//	| 1 byte  | 4 bytes    | 16     | 7 bytes      |         |     |            |
+//	+---------+------------+--------+--------------+---------+-----+------------+
+//
+// every chunk is sealed by AES-256-GCM with the key derived from the key and salt by HKDF-SHA256, the
+// nonce of a chunk is the nonce prefix, 4 bytes big-endian chunk index and 1 byte last chunk flag, so
+// reordered, truncated or appended chunks are detected. The header is authenticated by every chunk.
+package stream
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"math"
+)
+
+// Version is the format version written by NewWriter
+const Version byte = 1
+
+// DefaultChunkSize is the plaintext size of a chunk used by NewWriter
+const DefaultChunkSize = 64 * 1024
+
+const (
+	saltSize        = 16
+	noncePrefixSize = 7
+	headerSize      = 1 + 4 + saltSize + noncePrefixSize
+	tagSize         = 16
+	nonceSize       = 12
+)
+
+var (
+	ErrInvalidKey         = errors.New("stream: key must be at least 16 bytes")
+	ErrInvalidChunkSize   = errors.New("stream: invalid chunk size")
+	ErrUnsupportedVersion = errors.New("stream: unsupported version")
+	ErrTruncated          = errors.New("stream: truncated stream")
+	ErrAuthentication     = errors.New("stream: message authentication failed")
+	ErrTooLarge           = errors.New("stream: too many chunks")
+	ErrClosed             = errors.New("stream: write to closed writer")
+)
+
+// header is the parsed stream header
+type header struct {
+	raw         []byte
+	chunkSize   int
+	aead        cipher.AEAD
+	noncePrefix []byte
+}
+
+func newHeader(key []byte, chunkSize int) (*header, error) {
+	raw := make([]byte, headerSize)
+	raw[0] = Version
+	binary.BigEndian.PutUint32(raw[1:5], uint32(chunkSize))
+	if _, err := io.ReadFull(rand.Reader, raw[5:]); err != nil {
+		return nil, err
+	}
+	return parseHeader(key, raw)
+}
+
+func parseHeader(key, raw []byte) (*header, error) {
+	if len(key) < 16 {
+		return nil, ErrInvalidKey
+	}
+	if raw[0] != Version {
+		return nil, ErrUnsupportedVersion
+	}
+	chunkSize := binary.BigEndian.Uint32(raw[1:5])
+	if chunkSize == 0 || chunkSize > math.MaxInt32-tagSize {
+		return nil, ErrInvalidChunkSize
+	}
+	salt := raw[5 : 5+saltSize]
+	subKey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, salt, []byte("stream")), subKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(subKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &header{
+		raw:         raw,
+		chunkSize:   int(chunkSize),
+		aead:        aead,
+		noncePrefix: raw[5+saltSize:],
+	}, nil
+}
+
+func (h *header) nonce(index uint64, last bool) ([]byte, error) {
+	if index > math.MaxUint32 {
+		return nil, ErrTooLarge
+	}
+	nonce := make([]byte, nonceSize)
+	copy(nonce, h.noncePrefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], uint32(index))
+	if last {
+		nonce[nonceSize-1] = 1
+	}
+	return nonce, nil
+}
+
+func (h *header) seal(dst, chunk []byte, index uint64, last bool) ([]byte, error) {
+	nonce, err := h.nonce(index, last)
+	if err != nil {
+		return nil, err
+	}
+	return h.aead.Seal(dst, nonce, chunk, h.raw), nil
+}
+
+func (h *header) open(dst, chunk []byte, index uint64, last bool) ([]byte, error) {
+	nonce, err := h.nonce(index, last)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := h.aead.Open(dst, nonce, chunk, h.raw)
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	return plaintext, nil
+}
+
+// Writer encrypts the written data to the underlying writer, Close must be called to write the last
+// chunk
+type Writer struct {
+	w      io.Writer
+	h      *header
+	buf    []byte
+	out    []byte
+	index  uint64
+	err    error
+	closed bool
+}
+
+// NewWriter writes the header to w and returns the writer which encrypts with DefaultChunkSize
+func NewWriter(w io.Writer, key []byte) (*Writer, error) {
+	return NewWriterSize(w, key, DefaultChunkSize)
+}
+
+// NewWriterSize is the same as NewWriter but with the plaintext size of a chunk
+func NewWriterSize(w io.Writer, key []byte, chunkSize int) (*Writer, error) {
+	if chunkSize <= 0 || chunkSize > math.MaxInt32-tagSize {
+		return nil, ErrInvalidChunkSize
+	}
+	h, err := newHeader(key, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(h.raw); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		w:   w,
+		h:   h,
+		buf: make([]byte, 0, chunkSize),
+		out: make([]byte, 0, chunkSize+tagSize),
+	}, nil
+}
+
+// Write encrypts p, a full chunk is written when more data follows it, so the last chunk is known
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	for len(p) > 0 {
+		if len(w.buf) == w.h.chunkSize {
+			if w.err = w.flush(false); w.err != nil {
+				return n, w.err
+			}
+		}
+		size := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+size]
+		p = p[size:]
+		n += size
+	}
+	return n, nil
+}
+
+// Close writes the last chunk, it does not close the underlying writer
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.flush(true)
+	return w.err
+}
+
+func (w *Writer) flush(last bool) error {
+	out, err := w.h.seal(w.out[:0], w.buf, w.index, last)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(out); err != nil {
+		return err
+	}
+	w.index++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Reader decrypts the stream read from the underlying reader
+type Reader struct {
+	r     *bufio.Reader
+	h     *header
+	buf   []byte
+	plain []byte
+	index uint64
+	err   error
+}
+
+// NewReader reads the header from r and returns the reader which decrypts the chunks in order, the
+// data is returned only after its chunk is authenticated
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	raw := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrTruncated
+		}
+		return nil, err
+	}
+	h, err := parseHeader(key, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		r:   bufio.NewReader(r),
+		h:   h,
+		buf: make([]byte, h.chunkSize+tagSize),
+	}, nil
+}
+
+func (r *Reader) Read(p []byte) (n int, err error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.next()
+	}
+	n = copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// next decrypts the next chunk, the chunk is the last one if no data follows it
+func (r *Reader) next() error {
+	size, err := io.ReadFull(r.r, r.buf)
+	last := false
+	switch err {
+	case nil:
+		if _, err := r.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		if size < tagSize {
+			return ErrTruncated
+		}
+		last = true
+	default:
+		return err
+	}
+	r.plain, err = r.h.open(r.buf[:0], r.buf[:size], r.index, last)
+	if err != nil {
+		return err
+	}
+	r.index++
+	if last {
+		return io.EOF
+	}
+	return nil
+}
+
+// ReaderAt decrypts the stream at random offsets, such as the range reads of http.ServeContent:
+//
+//	ra, err := stream.NewReaderAt(file, info.Size(), key)
+//	http.ServeContent(w, req, name, modTime, io.NewSectionReader(ra, 0, ra.Size()))
+//
+// it is safe for concurrent use if the underlying ReaderAt is
+type ReaderAt struct {
+	r      io.ReaderAt
+	h      *header
+	chunks uint64
+	size   int64
+	encEnd int64
+}
+
+// NewReaderAt reads the header of the stream of size bytes
+func NewReaderAt(r io.ReaderAt, size int64, key []byte) (*ReaderAt, error) {
+	if size < headerSize+tagSize {
+		return nil, ErrTruncated
+	}
+	raw := make([]byte, headerSize)
+	if _, err := r.ReadAt(raw, 0); err != nil {
+		return nil, err
+	}
+	h, err := parseHeader(key, raw)
+	if err != nil {
+		return nil, err
+	}
+	encChunk := int64(h.chunkSize + tagSize)
+	body := size - headerSize
+	chunks := (body + encChunk - 1) / encChunk
+	if rest := body % encChunk; rest != 0 && rest < tagSize {
+		return nil, ErrTruncated
+	}
+	return &ReaderAt{
+		r:      r,
+		h:      h,
+		chunks: uint64(chunks),
+		size:   body - chunks*tagSize,
+		encEnd: size,
+	}, nil
+}
+
+// Size returns the plaintext size
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+// ReadAt decrypts the chunks which cover p at off
+func (r *ReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("stream: negative offset")
+	}
+	chunkSize := int64(r.h.chunkSize)
+	buf := make([]byte, chunkSize+tagSize)
+	for len(p) > 0 {
+		if off >= r.size {
+			return n, io.EOF
+		}
+		index := off / chunkSize
+		plain, err := r.chunk(buf, uint64(index))
+		if err != nil {
+			return n, err
+		}
+		size := copy(p, plain[off-index*chunkSize:])
+		p = p[size:]
+		off += int64(size)
+		n += size
+	}
+	return n, nil
+}
+
+// chunk reads and decrypts the chunk of index to buf
+func (r *ReaderAt) chunk(buf []byte, index uint64) ([]byte, error) {
+	encChunk := int64(r.h.chunkSize + tagSize)
+	start := headerSize + int64(index)*encChunk
+	end := start + encChunk
+	if end > r.encEnd {
+		end = r.encEnd
+	}
+	buf = buf[:end-start]
+	if _, err := r.r.ReadAt(buf, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return r.h.open(buf[:0], buf, index, index == r.chunks-1)
+}
@@ -0,0 +1,115 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+var testKey = []byte("change this pass")
+
+func encrypt(t *testing.T, plaintext []byte, chunkSize int) []byte {
+	buf := new(bytes.Buffer)
+	w, err := NewWriterSize(buf, testKey, chunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// write in odd pieces to cross the chunk boundaries
+	for p := plaintext; len(p) > 0; {
+		size := 7
+		if size > len(p) {
+			size = len(p)
+		}
+		if _, err := w.Write(p[:size]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[size:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestStream(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{0, 1, 31, 32, 64, 100} {
+		plaintext := make([]byte, size)
+		r.Read(plaintext)
+		ciphertext := encrypt(t, plaintext, 32)
+
+		reader, err := NewReader(bytes.NewReader(ciphertext), testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("%d: %v", size, err)
+		}
+		if !bytes.Equal(plaintext, got) {
+			t.Errorf("%d: decrypted data mismatch\n", size)
+		}
+
+		ra, err := NewReaderAt(bytes.NewReader(ciphertext), int64(len(ciphertext)), testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ra.Size() != int64(size) {
+			t.Errorf("%d: need size: %d, got: %d\n", size, size, ra.Size())
+		}
+		for i := 0; i < 20 && size > 0; i++ {
+			off := r.Intn(size)
+			length := r.Intn(size-off) + 1
+			p := make([]byte, length)
+			n, err := ra.ReadAt(p, int64(off))
+			if err != nil && err != io.EOF {
+				t.Fatalf("%d: read at %d: %v", size, off, err)
+			}
+			if !bytes.Equal(plaintext[off:off+length], p[:n]) {
+				t.Errorf("%d: read at %d mismatch\n", size, off)
+			}
+		}
+	}
+}
+
+func TestStreamTampered(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("0123456789"), 10)
+	ciphertext := encrypt(t, plaintext, 32)
+	encChunk := 32 + tagSize
+
+	flipped := append([]byte(nil), ciphertext...)
+	flipped[headerSize+encChunk+1] ^= 1
+	// drop the last chunk, the previous chunk is not flagged as the last one
+	truncated := ciphertext[:headerSize+3*encChunk]
+	// swap the first two chunks
+	swapped := append([]byte(nil), ciphertext[:headerSize]...)
+	swapped = append(swapped, ciphertext[headerSize+encChunk:headerSize+2*encChunk]...)
+	swapped = append(swapped, ciphertext[headerSize:headerSize+encChunk]...)
+	swapped = append(swapped, ciphertext[headerSize+2*encChunk:]...)
+
+	for name, data := range map[string][]byte{"flipped": flipped, "truncated": truncated, "swapped": swapped} {
+		reader, err := NewReader(bytes.NewReader(data), testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ioutil.ReadAll(reader); err != ErrAuthentication {
+			t.Errorf("%s: need: %v, got: %v\n", name, ErrAuthentication, err)
+		}
+		ra, err := NewReaderAt(bytes.NewReader(data), int64(len(data)), testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ioutil.ReadAll(io.NewSectionReader(ra, 0, ra.Size())); err != ErrAuthentication {
+			t.Errorf("%s: need: %v, got: %v\n", name, ErrAuthentication, err)
+		}
+	}
+
+	if _, err := NewReader(bytes.NewReader(ciphertext[:10]), testKey); err != ErrTruncated {
+		t.Errorf("need: %v, got: %v\n", ErrTruncated, err)
+	}
+	if _, err := NewReader(bytes.NewReader(ciphertext), []byte("short")); err != ErrInvalidKey {
+		t.Errorf("need: %v, got: %v\n", ErrInvalidKey, err)
+	}
+}
@@ -12,7 +12,7 @@ type Config struct {
 	// Expired connections may be closed lazily before reuse.
 	//
 	// If MaxLifetime <= 0, connections are reused forever.
-	MaxLifetime int `toml:"max_lifetime"`
+	MaxLifetime int `toml:"max_lifetime" default:"0" desc:"最长等待断开时间(单位: 秒), 如果该值为 0, 则不限制时间" validate:"min=0"`
 
 	// MaxOpenConns sets the maximum number of open connections to the database.
 	//
@@ -21,7 +21,7 @@ type Config struct {
 	// MaxOpenConns limit.
 	//
 	// If MaxOpenConns <= 0, then there is no limit on the number of open connections.
-	MaxOpenConns int `toml:"max_open_conns"`
+	MaxOpenConns int `toml:"max_open_conns" default:"10" desc:"最多打开数据库的连接数量, 如果该值为 0, 则不限制连接数量" validate:"min=0"`
 
 	// MaxIdleConns is the maximum number of connections in the idle
 	// connection pool.
@@ -30,7 +30,7 @@ type Config struct {
 	// then the new MaxIdleConns will be reduced to match the MaxOpenConns limit.
 	//
 	// If MaxIdleConns <= 0, no idle connections are retained.
-	MaxIdleConns int `toml:"max_idle_conns"`
+	MaxIdleConns int `toml:"max_idle_conns" default:"10" desc:"连接池中最多空闲链接数量, 如果该值为 0, 则不保留空闲链接" validate:"min=0"`
 }
 
 func (e Config) Init(db *sql.DB) error {
@@ -8,36 +8,17 @@ import (
 	"github.com/morgine/pkg/database"
 )
 
-/**
-# mysql 数据库配置
-[mysql]
-# 连接地址
-host = "127.0.0.1"
-# 连接端口
-port = 3306
-# 用户名
-user = "root"
-# 密码
-password = "123456"
-# 数据库
-db_name = ""
-# 连接参数
-parameters = "charset=utf8mb4&parseTime=True&loc=Local&allowNativePasswords=true"
-# 最长等待断开时间(单位: 秒), 如果该值为 0, 则不限制时间
-max_lifetime = 0
-# 最多打开数据库的连接数量, 如果该值为 0, 则不限制连接数量
-max_open_conns = 10
-# 连接池中最多空闲链接数量, 如果该值为 0, 则不保留空闲链接
-max_idle_conns = 10
-*/
+func init() {
+	config.RegisterSchema("mysql", "mysql 数据库配置", Config{})
+}
 
 type Config struct {
-	Host       string `toml:"host"`
-	Port       int    `toml:"port"`
-	User       string `toml:"user"`
-	Password   string `toml:"password"`
-	DBName     string `toml:"db_name"`
-	Parameters string `toml:"parameters"`
+	Host       string `toml:"host" default:"127.0.0.1" desc:"连接地址" validate:"required"`
+	Port       int    `toml:"port" default:"3306" desc:"连接端口" validate:"min=1,max=65535"`
+	User       string `toml:"user" default:"root" desc:"用户名" validate:"required"`
+	Password   string `toml:"password" desc:"密码" secret:"true"`
+	DBName     string `toml:"db_name" desc:"数据库"`
+	Parameters string `toml:"parameters" default:"charset=utf8mb4&parseTime=True&loc=Local&allowNativePasswords=true" desc:"连接参数"`
 	database.Config
 }
 
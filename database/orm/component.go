@@ -0,0 +1,27 @@
+package orm
+
+import (
+	"database/sql"
+	"github.com/morgine/pkg/app"
+	"gorm.io/gorm"
+)
+
+// Component gorm 组件, 由 namespace 下的配置及 db 组件(*sql.DB)初始化 ORM, 实例类型为 *gorm.DB,
+// 数据库连接由 db 组件关闭, 如:
+//
+//	app.Register(mysql.Component("mysql", "mysql"))
+//	app.Register(orm.Component("gorm", "gorm", "mysql", orm.NewMysqlDialector))
+func Component(name, namespace, db string, dialector func(db *sql.DB) gorm.Dialector) app.Component {
+	return app.Component{
+		Name:      name,
+		Namespace: namespace,
+		Config:    Config{},
+		Requires:  []string{db},
+		New: func(c *app.Context) (interface{}, error) {
+			return c.Config().(*Config).Init(dialector(c.Get(db).(*sql.DB)))
+		},
+		Close: func(interface{}) error {
+			return nil
+		},
+	}
+}
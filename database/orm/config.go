@@ -2,6 +2,7 @@ package orm
 
 import (
 	"database/sql"
+	"github.com/morgine/pkg/config"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -9,21 +10,14 @@ import (
 	"gorm.io/gorm/schema"
 )
 
-/**
-# gorm 配置
-[gorm]
-# 日志等级 1-Silent, 2-Error, 3-Warn, 4-Info
-log_level = 4
-# 数据库表名前缀
-table_prefix = ""
-# 使用单数表名
-singular_table = false
-*/
+func init() {
+	config.RegisterSchema("gorm", "gorm 配置", Config{})
+}
 
 type Config struct {
-	LogLevel      logger.LogLevel `toml:"log_level"`
-	TablePrefix   string          `toml:"table_prefix"`
-	SingularTable bool            `toml:"singular_table"`
+	LogLevel      logger.LogLevel `toml:"log_level" default:"4" desc:"日志等级 1-Silent, 2-Error, 3-Warn, 4-Info" validate:"min=0,max=4"`
+	TablePrefix   string          `toml:"table_prefix" desc:"数据库表名前缀"`
+	SingularTable bool            `toml:"singular_table" desc:"使用单数表名"`
 }
 
 func (e *Config) Init(dialector gorm.Dialector) (*gorm.DB, error) {
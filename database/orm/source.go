@@ -0,0 +1,63 @@
+package orm
+
+import (
+	"context"
+	"github.com/morgine/pkg/config"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ConfigItem 配置项, Key 为点分隔的配置路径(如 "mysql.host")
+type ConfigItem struct {
+	ID        int
+	Key       string `gorm:"uniqueIndex;size:191"`
+	Value     string
+	UpdatedAt time.Time
+}
+
+// ConfigSource 以数据库表作为配置源
+type ConfigSource struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// NewConfigSource 创建配置源并自动迁移配置表, interval 为监听变化的轮询间隔
+func NewConfigSource(db *gorm.DB, interval time.Duration) (*ConfigSource, error) {
+	err := db.AutoMigrate(&ConfigItem{})
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigSource{db: db, interval: interval}, nil
+}
+
+// Load 读取所有配置项
+func (s *ConfigSource) Load() (config.Configs, error) {
+	var items []*ConfigItem
+	err := s.db.Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	kvs := make(map[string]string, len(items))
+	for _, item := range items {
+		kvs[item.Key] = item.Value
+	}
+	return config.FromKeyValues(kvs), nil
+}
+
+// Set 保存配置项, 配置项已存在则覆盖
+func (s *ConfigSource) Set(key, value string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		item := &ConfigItem{}
+		err := tx.Where(&ConfigItem{Key: key}).First(item).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		item.Key, item.Value = key, value
+		return tx.Save(item).Error
+	})
+}
+
+// Watch 轮询配置表, 配置发生变化时调用 onChange
+func (s *ConfigSource) Watch(ctx context.Context, onChange func(cs config.Configs)) error {
+	return config.PollSource(ctx, s.interval, s.Load, onChange)
+}
@@ -0,0 +1,37 @@
+package orm
+
+import (
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+	"time"
+)
+
+func TestConfigSource(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewConfigSource(db, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{"redis.addr": "localhost:6379", "redis.db": "1"} {
+		err = source.Set(key, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = source.Set("redis.db", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := source.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr, db := cs.GetStr("redis.addr"), cs.GetInt("redis.db"); addr != "localhost:6379" || db != 2 {
+		t.Errorf("got: %s %d\n", addr, db)
+	}
+}
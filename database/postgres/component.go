@@ -0,0 +1,15 @@
+package postgres
+
+import "github.com/morgine/pkg/app"
+
+// Component 数据库组件, 由 namespace 下的配置连接数据库, 实例类型为 *sql.DB
+func Component(name, namespace string) app.Component {
+	return app.Component{
+		Name:      name,
+		Namespace: namespace,
+		Config:    Config{},
+		New: func(c *app.Context) (interface{}, error) {
+			return c.Config().(*Config).Connect()
+		},
+	}
+}
@@ -0,0 +1,15 @@
+package redis
+
+import "github.com/morgine/pkg/app"
+
+// Component redis 组件, 由 namespace 下的配置连接 redis, 实例类型为 *redis.Client
+func Component(name, namespace string) app.Component {
+	return app.Component{
+		Name:      name,
+		Namespace: namespace,
+		Config:    Config{},
+		New: func(c *app.Context) (interface{}, error) {
+			return c.Config().(*Config).Connect()
+		},
+	}
+}
@@ -6,21 +6,14 @@ import (
 	"github.com/morgine/pkg/config"
 )
 
-/**
-# redis 数据库配置
-[redis]
-# redis 地址
-addr = "localhost:6379"
-# redis 密码
-password = ""
-# db 索引
-db = 0
-*/
+func init() {
+	config.RegisterSchema("redis", "redis 数据库配置", Config{})
+}
 
 type Config struct {
-	Addr     string `toml:"addr"`
-	Password string `toml:"password"`
-	DB       int    `toml:"db"`
+	Addr     string `toml:"addr" default:"localhost:6379" desc:"redis 地址" validate:"required,hostport"`
+	Password string `toml:"password" desc:"redis 密码" secret:"true"`
+	DB       int    `toml:"db" desc:"db 索引" validate:"min=0"`
 }
 
 func (e Config) Connect() (*redis.Client, error) {
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/morgine/pkg/config"
+	"time"
+)
+
+// ConfigSource 以 redis hash 作为配置源, hash 的 field 为点分隔的配置路径(如 "mysql.host"),
+// value 为配置值(如 "127.0.0.1", 数字及 JSON 数组会被自动解析)
+type ConfigSource struct {
+	client   *redis.Client
+	key      string
+	interval time.Duration
+}
+
+// NewConfigSource 创建配置源, interval 为监听变化的轮询间隔
+func NewConfigSource(client *redis.Client, key string, interval time.Duration) *ConfigSource {
+	return &ConfigSource{client: client, key: key, interval: interval}
+}
+
+// Load 读取 hash 中的所有配置
+func (s *ConfigSource) Load() (config.Configs, error) {
+	kvs, err := s.client.HGetAll(context.Background(), s.key).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	return config.FromKeyValues(kvs), nil
+}
+
+// Watch 轮询 hash, 配置发生变化时调用 onChange
+func (s *ConfigSource) Watch(ctx context.Context, onChange func(cs config.Configs)) error {
+	return config.PollSource(ctx, s.interval, s.Load, onChange)
+}
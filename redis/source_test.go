@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/morgine/pkg/config"
+	"testing"
+	"time"
+)
+
+func TestConfigSource(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.HSet("configs", "mysql.host", "10.0.0.1", "mysql.port", "3307", "mysql.password", "123456", "mysql.max_open_conns", "20")
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	source := NewConfigSource(client, "configs", 10*time.Millisecond)
+	base, err := config.UnmarshalMemory([]byte(`
+[mysql]
+host = "127.0.0.1"
+port = 3306
+user = "root"
+password = ""
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, _, err := config.NewLoader().Add("base", base).AddSource("redis", source).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host, port, user := cs.GetStr("mysql.host"), cs.Get("mysql.port"), cs.GetStr("mysql.user"); host != "10.0.0.1" || port != int64(3307) || user != "root" {
+		t.Errorf("got: %s %v %s\n", host, port, user)
+	}
+	// numeric-looking values of string fields stay strings, keys only defined by the source are
+	// parsed to the field types
+	var mysql struct {
+		Port         int    `toml:"port"`
+		Password     string `toml:"password"`
+		MaxOpenConns int    `toml:"max_open_conns"`
+	}
+	err = cs.UnmarshalSub("mysql", &mysql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mysql.Port != 3307 || mysql.Password != "123456" || mysql.MaxOpenConns != 20 {
+		t.Errorf("need: 3307 123456 20, got: %d %s %d\n", mysql.Port, mysql.Password, mysql.MaxOpenConns)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan config.Configs, 1)
+	go source.Watch(ctx, func(cs config.Configs) {
+		changes <- cs
+	})
+	time.Sleep(20 * time.Millisecond)
+	s.HSet("configs", "mysql.host", "10.0.0.2")
+	select {
+	case cs := <-changes:
+		if got := cs.GetStr("mysql.host"); got != "10.0.0.2" {
+			t.Errorf("need: 10.0.0.2, got: %s\n", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch timeout")
+	}
+}
@@ -0,0 +1,41 @@
+package session
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/morgine/pkg/app"
+	"gorm.io/gorm"
+	"time"
+)
+
+// RedisComponent redis token 存储器组件, client 为 redis 组件(*redis.Client)名称, 实例类型为 Storage
+func RedisComponent(name, keyPrefix, client string) app.Component {
+	return app.Component{
+		Name:     name,
+		Requires: []string{client},
+		New: func(c *app.Context) (interface{}, error) {
+			return NewRedisStorage(keyPrefix, c.Get(client).(*redis.Client)), nil
+		},
+	}
+}
+
+// MemoryComponent 内存 token 存储器组件, cleanupInterval 为后台清理间隔, 实例类型为 *MemoryStorage
+func MemoryComponent(name string, cleanupInterval time.Duration) app.Component {
+	return app.Component{
+		Name: name,
+		New: func(c *app.Context) (interface{}, error) {
+			return NewMemoryStorage(cleanupInterval), nil
+		},
+	}
+}
+
+// SQLComponent 数据库 token 存储器组件, db 为 gorm 组件(*gorm.DB)名称, purgeInterval 为过期 token 清理间隔,
+// 实例类型为 *SQLStorage
+func SQLComponent(name, db string, purgeInterval time.Duration) app.Component {
+	return app.Component{
+		Name:     name,
+		Requires: []string{db},
+		New: func(c *app.Context) (interface{}, error) {
+			return NewSQLStorage(c.Get(db).(*gorm.DB), purgeInterval)
+		},
+	}
+}
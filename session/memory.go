@@ -0,0 +1,154 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStorage 内存 token 存储器, 适用于测试及单节点部署, 并发安全.
+// 过期 token 在读取时惰性删除, 并由后台清理协程定期清除
+type MemoryStorage struct {
+	mu    sync.Mutex
+	users map[string]map[string]time.Time // 用户 ID => token => 过期时间, 零值表示永不过期
+	now   func() time.Time
+	stop  chan struct{}
+	once  sync.Once
+}
+
+// NewMemoryStorage 创建内存存储器, cleanupInterval 为后台清理间隔, 小于等于 0 时不启动后台清理,
+// 不再使用时需调用 Close 停止清理协程
+func NewMemoryStorage(cleanupInterval time.Duration) *MemoryStorage {
+	ms := &MemoryStorage{
+		users: make(map[string]map[string]time.Time),
+		now:   time.Now,
+		stop:  make(chan struct{}),
+	}
+	if cleanupInterval > 0 {
+		go ms.janitor(cleanupInterval)
+	}
+	return ms
+}
+
+// SetNow 设置时钟, 用于测试中模拟时间流逝
+func (ms *MemoryStorage) SetNow(now func() time.Time) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.now = now
+}
+
+// Close 停止后台清理
+func (ms *MemoryStorage) Close() error {
+	ms.once.Do(func() {
+		close(ms.stop)
+	})
+	return nil
+}
+
+func (ms *MemoryStorage) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ms.Purge()
+		case <-ms.stop:
+			return
+		}
+	}
+}
+
+// Purge 清除所有过期 token
+func (ms *MemoryStorage) Purge() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	now := ms.now()
+	for userID, tokens := range ms.users {
+		for token, expiresAt := range tokens {
+			if isExpired(expiresAt, now) {
+				delete(tokens, token)
+			}
+		}
+		if len(tokens) == 0 {
+			delete(ms.users, userID)
+		}
+	}
+}
+
+// Len 返回未过期的 token 数量
+func (ms *MemoryStorage) Len() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	now := ms.now()
+	n := 0
+	for _, tokens := range ms.users {
+		for _, expiresAt := range tokens {
+			if !isExpired(expiresAt, now) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func (ms *MemoryStorage) SaveToken(userID, token string, expires int64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	tokens := ms.users[userID]
+	if tokens == nil {
+		tokens = make(map[string]time.Time)
+		ms.users[userID] = tokens
+	}
+	tokens[token] = ms.expiresAt(expires)
+	return nil
+}
+
+func (ms *MemoryStorage) CheckAndRefreshToken(userID, token string, expires int64) (ok bool, err error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	tokens := ms.users[userID]
+	expiresAt, ok := tokens[token]
+	if !ok {
+		return false, nil
+	}
+	if isExpired(expiresAt, ms.now()) {
+		ms.removeToken(userID, token)
+		return false, nil
+	}
+	tokens[token] = ms.expiresAt(expires)
+	return true, nil
+}
+
+func (ms *MemoryStorage) RemoveToken(userID, token string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.removeToken(userID, token)
+	return nil
+}
+
+func (ms *MemoryStorage) RemoveUser(userID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.users, userID)
+	return nil
+}
+
+func (ms *MemoryStorage) removeToken(userID, token string) {
+	if tokens := ms.users[userID]; tokens != nil {
+		delete(tokens, token)
+		if len(tokens) == 0 {
+			delete(ms.users, userID)
+		}
+	}
+}
+
+// expiresAt 返回 expires 秒后的过期时间, expires 小于等于 0 时永不过期
+func (ms *MemoryStorage) expiresAt(expires int64) time.Time {
+	if expires <= 0 {
+		return time.Time{}
+	}
+	return ms.now().Add(time.Duration(expires) * time.Second)
+}
+
+func isExpired(expiresAt, now time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
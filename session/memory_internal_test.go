@@ -0,0 +1,69 @@
+package session
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// size returns the number of stored tokens, expired tokens included
+func (ms *MemoryStorage) size() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	n := 0
+	for _, tokens := range ms.users {
+		n += len(tokens)
+	}
+	return n
+}
+
+func TestMemoryStorageJanitor(t *testing.T) {
+	ms := NewMemoryStorage(time.Millisecond)
+	defer ms.Close()
+	var mu sync.Mutex
+	now := time.Now()
+	ms.SetNow(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+	_ = ms.SaveToken("1", "a", 10)
+	_ = ms.SaveToken("1", "b", 30)
+	_ = ms.SaveToken("2", "c", 10)
+	mu.Lock()
+	now = now.Add(20 * time.Second)
+	mu.Unlock()
+	// expired tokens are removed by the janitor without being read
+	deadline := time.Now().Add(time.Second)
+	for ms.size() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := ms.size(); n != 1 {
+		t.Errorf("need: 1, got: %d\n", n)
+	}
+	ms.mu.Lock()
+	_, ok := ms.users["2"]
+	ms.mu.Unlock()
+	if ok {
+		t.Error("need user without tokens removed")
+	}
+}
+
+func TestMemoryStorageWithoutJanitor(t *testing.T) {
+	ms := NewMemoryStorage(0)
+	defer ms.Close()
+	now := time.Now()
+	ms.SetNow(func() time.Time {
+		return now
+	})
+	_ = ms.SaveToken("1", "a", 10)
+	_ = ms.SaveToken("1", "b", 30)
+	now = now.Add(20 * time.Second)
+	if n := ms.size(); n != 2 {
+		t.Errorf("need: 2, got: %d\n", n)
+	}
+	ms.Purge()
+	if n := ms.size(); n != 1 {
+		t.Errorf("need: 1, got: %d\n", n)
+	}
+}
@@ -0,0 +1,29 @@
+package session_test
+
+import (
+	"github.com/morgine/pkg/session"
+	"github.com/morgine/pkg/session/storagetest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T) (session.Storage, func(d time.Duration)) {
+			var mu sync.Mutex
+			now := time.Now()
+			ms := session.NewMemoryStorage(0)
+			ms.SetNow(func() time.Time {
+				mu.Lock()
+				defer mu.Unlock()
+				return now
+			})
+			return ms, func(d time.Duration) {
+				mu.Lock()
+				defer mu.Unlock()
+				now = now.Add(d)
+			}
+		},
+	})
+}
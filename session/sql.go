@@ -0,0 +1,129 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+// SessionToken 会话 token 记录, user_id 与 token_hash 联合唯一, 其索引同时用于按用户删除 token.
+// 表中只保存 token 的 SHA-256 摘要, 既固定了列长度, 也避免泄露可直接使用的 token
+type SessionToken struct {
+	ID        int64
+	UserID    string     `gorm:"size:64;not null;uniqueIndex:idx_session_user_token,priority:1"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex:idx_session_user_token,priority:2"`
+	ExpiresAt *time.Time `gorm:"index"` // 过期时间, NULL 表示永不过期
+	Refreshes int64      // 刷新次数, 保证每次刷新都修改记录, mysql 默认只返回实际修改的行数
+}
+
+// SQLStorage 数据库 token 存储器, 适用于没有 redis 的部署, 过期 token 在读取时视作不存在,
+// 并由后台清理协程定期删除
+type SQLStorage struct {
+	db   *gorm.DB
+	mu   sync.RWMutex
+	now  func() time.Time
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewSQLStorage 创建数据库存储器并迁移 token 表, db 可由 orm.NewMysqlORM 或 orm.NewPostgresORM 创建,
+// purgeInterval 为清理过期 token 的间隔, 小于等于 0 时不启动后台清理, 不再使用时需调用 Close 停止清理协程
+func NewSQLStorage(db *gorm.DB, purgeInterval time.Duration) (*SQLStorage, error) {
+	err := db.AutoMigrate(&SessionToken{})
+	if err != nil {
+		return nil, err
+	}
+	ss := &SQLStorage{db: db, now: time.Now, stop: make(chan struct{})}
+	if purgeInterval > 0 {
+		go ss.janitor(purgeInterval)
+	}
+	return ss, nil
+}
+
+// SetNow 设置时钟, 用于测试中模拟时间流逝
+func (ss *SQLStorage) SetNow(now func() time.Time) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.now = now
+}
+
+// Close 停止后台清理, 不关闭数据库连接
+func (ss *SQLStorage) Close() error {
+	ss.once.Do(func() {
+		close(ss.stop)
+	})
+	return nil
+}
+
+func (ss *SQLStorage) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = ss.Purge()
+		case <-ss.stop:
+			return
+		}
+	}
+}
+
+// Purge 删除所有过期 token
+func (ss *SQLStorage) Purge() error {
+	return ss.db.Where("expires_at <= ?", ss.current()).Delete(&SessionToken{}).Error
+}
+
+func (ss *SQLStorage) SaveToken(userID, token string, expires int64) error {
+	return ss.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "token_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+	}).Create(&SessionToken{UserID: userID, TokenHash: hashToken(token), ExpiresAt: ss.expiresAt(expires)}).Error
+}
+
+func (ss *SQLStorage) CheckAndRefreshToken(userID, token string, expires int64) (ok bool, err error) {
+	// 检测与刷新在同一条语句中完成, 更新的行数即检测结果
+	result := ss.db.Model(&SessionToken{}).
+		Where("user_id = ? AND token_hash = ?", userID, hashToken(token)).
+		Where("expires_at IS NULL OR expires_at > ?", ss.current()).
+		Updates(map[string]interface{}{
+			"expires_at": ss.expiresAt(expires),
+			"refreshes":  gorm.Expr("refreshes + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (ss *SQLStorage) RemoveToken(userID, token string) error {
+	return ss.db.Where("user_id = ? AND token_hash = ?", userID, hashToken(token)).Delete(&SessionToken{}).Error
+}
+
+func (ss *SQLStorage) RemoveUser(userID string) error {
+	return ss.db.Where("user_id = ?", userID).Delete(&SessionToken{}).Error
+}
+
+// hashToken 返回 token 的 SHA-256 十六进制摘要
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// current 返回 UTC 当前时间, 统一时区以便数据库比较
+func (ss *SQLStorage) current() time.Time {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return ss.now().UTC()
+}
+
+// expiresAt 返回 expires 秒后的过期时间, expires 小于等于 0 时永不过期
+func (ss *SQLStorage) expiresAt(expires int64) *time.Time {
+	if expires <= 0 {
+		return nil
+	}
+	t := ss.current().Add(time.Duration(expires) * time.Second)
+	return &t
+}
@@ -0,0 +1,92 @@
+package session_test
+
+import (
+	"github.com/morgine/pkg/session"
+	"github.com/morgine/pkg/session/storagetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newSQLStorage(t *testing.T) (*session.SQLStorage, *gorm.DB, func(d time.Duration)) {
+	// a shared cache keeps the in-memory database for every connection of the pool
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	ss, err := session.NewSQLStorage(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	now := time.Now()
+	ss.SetNow(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+	return ss, db, func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+func TestSQLStorage(t *testing.T) {
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T) (session.Storage, func(d time.Duration)) {
+			ss, _, advance := newSQLStorage(t)
+			return ss, advance
+		},
+	})
+}
+
+func TestSQLStoragePurge(t *testing.T) {
+	ss, db, advance := newSQLStorage(t)
+	_ = ss.SaveToken("1", "a", 10)
+	_ = ss.SaveToken("1", "b", 30)
+	_ = ss.SaveToken("1", "c", 0)
+	advance(20 * time.Second)
+	if err := ss.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	if err := db.Model(&session.SessionToken{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("need: 2, got: %d\n", count)
+	}
+}
+
+func TestSQLStorageHashesTokens(t *testing.T) {
+	ss, db, _ := newSQLStorage(t)
+	// RS256 tokens exceed the size of common indexed columns
+	token := strings.Repeat("t", 512)
+	if err := ss.SaveToken("1", token, 60); err != nil {
+		t.Fatal(err)
+	}
+	var rows []session.SessionToken
+	if err := db.Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || len(rows[0].TokenHash) != 64 || strings.Contains(rows[0].TokenHash, "t") {
+		t.Errorf("need: the SHA-256 digest of the token, got: %+v\n", rows)
+	}
+	ok, err := ss.CheckAndRefreshToken("1", token, 60)
+	if err != nil || !ok {
+		t.Errorf("need: true, got: %v, %v\n", ok, err)
+	}
+}
@@ -27,7 +27,8 @@ func (rs *RedisStorage) RemoveToken(userID, token string) error {
 }
 
 func (rs *RedisStorage) RemoveUser(userID string) error {
-	keys, err := rs.client.Keys(noCtx, rs.userKey(userID)).Result()
+	// token 的键为 tokenKey(userID, token), 按 "<前缀><用户 ID>_*" 匹配, 仅匹配 userKey 的模式不会命中任何 token
+	keys, err := rs.client.Keys(noCtx, rs.tokenKey(userID, "*")).Result()
 	if err != nil && err != redis.Nil {
 		return err
 	}
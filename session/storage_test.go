@@ -0,0 +1,27 @@
+package session_test
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/morgine/pkg/session"
+	"github.com/morgine/pkg/session/storagetest"
+	"testing"
+	"time"
+)
+
+func TestRedisStorage(t *testing.T) {
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T) (session.Storage, func(d time.Duration)) {
+			s, err := miniredis.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			t.Cleanup(func() {
+				client.Close()
+				s.Close()
+			})
+			return session.NewRedisStorage("admin_", client), s.FastForward
+		},
+	})
+}
@@ -0,0 +1,191 @@
+// Package storagetest is the conformance test suite of session.Storage implementations:
+//
+//	func TestMemoryStorage(t *testing.T) {
+//		storagetest.Run(t, storagetest.Harness{
+//			New: func(t *testing.T) (session.Storage, func(d time.Duration)) {
+//				now := time.Now()
+//				ms := session.NewMemoryStorage(0)
+//				ms.SetNow(func() time.Time { return now })
+//				return ms, func(d time.Duration) { now = now.Add(d) }
+//			},
+//		})
+//	}
+package storagetest
+
+import (
+	"fmt"
+	"github.com/morgine/pkg/session"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Harness creates the storages under test
+type Harness struct {
+	// New returns an empty storage and the function which advances its clock by d, the expiry cases
+	// are skipped if advance is nil
+	New func(t *testing.T) (storage session.Storage, advance func(d time.Duration))
+}
+
+// Run runs every case of the suite as a sub test
+func Run(t *testing.T, h Harness) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, s session.Storage, advance func(d time.Duration))
+	}{
+		{"SaveAndCheck", testSaveAndCheck},
+		{"UnknownToken", testUnknownToken},
+		{"Expire", testExpire},
+		{"Refresh", testRefresh},
+		{"RemoveToken", testRemoveToken},
+		{"RemoveUser", testRemoveUser},
+		{"EmptyToken", testEmptyToken},
+		{"EmptyUser", testEmptyUser},
+		{"Concurrent", testConcurrent},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			s, advance := h.New(t)
+			c.fn(t, s, advance)
+		})
+	}
+}
+
+func check(t *testing.T, s session.Storage, userID, token string, need bool) {
+	t.Helper()
+	ok, err := s.CheckAndRefreshToken(userID, token, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok != need {
+		t.Errorf("CheckAndRefreshToken(%q, %q) need: %v, got: %v\n", userID, token, need, ok)
+	}
+}
+
+func save(t *testing.T, s session.Storage, userID, token string, expires int64) {
+	t.Helper()
+	if err := s.SaveToken(userID, token, expires); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testSaveAndCheck(t *testing.T, s session.Storage, _ func(time.Duration)) {
+	save(t, s, "1", "a", 60)
+	save(t, s, "1", "b", 60)
+	check(t, s, "1", "a", true)
+	check(t, s, "1", "b", true)
+}
+
+func testUnknownToken(t *testing.T, s session.Storage, _ func(time.Duration)) {
+	save(t, s, "1", "a", 60)
+	check(t, s, "1", "x", false)
+	check(t, s, "2", "a", false)
+}
+
+func testExpire(t *testing.T, s session.Storage, advance func(time.Duration)) {
+	if advance == nil {
+		t.Skip("clock can not be advanced")
+	}
+	save(t, s, "1", "a", 10)
+	save(t, s, "1", "b", 30)
+	advance(11 * time.Second)
+	check(t, s, "1", "a", false)
+	check(t, s, "1", "b", true)
+}
+
+func testRefresh(t *testing.T, s session.Storage, advance func(time.Duration)) {
+	if advance == nil {
+		t.Skip("clock can not be advanced")
+	}
+	save(t, s, "1", "a", 10)
+	advance(8 * time.Second)
+	// refreshed to expire 60 seconds later
+	check(t, s, "1", "a", true)
+	advance(30 * time.Second)
+	check(t, s, "1", "a", true)
+	advance(61 * time.Second)
+	check(t, s, "1", "a", false)
+}
+
+func testRemoveToken(t *testing.T, s session.Storage, _ func(time.Duration)) {
+	save(t, s, "1", "a", 60)
+	save(t, s, "1", "b", 60)
+	if err := s.RemoveToken("1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, "1", "a", false)
+	check(t, s, "1", "b", true)
+	// removing an unknown token is not an error
+	if err := s.RemoveToken("1", "x"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testRemoveUser(t *testing.T, s session.Storage, _ func(time.Duration)) {
+	save(t, s, "1", "a", 60)
+	save(t, s, "1", "b", 60)
+	// user IDs sharing a prefix must not be affected
+	save(t, s, "11", "c", 60)
+	save(t, s, "2", "d", 60)
+	if err := s.RemoveUser("1"); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, "1", "a", false)
+	check(t, s, "1", "b", false)
+	check(t, s, "11", "c", true)
+	check(t, s, "2", "d", true)
+	if err := s.RemoveUser("3"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testEmptyToken(t *testing.T, s session.Storage, _ func(time.Duration)) {
+	save(t, s, "1", "a", 60)
+	// an empty token matches no token of the user
+	check(t, s, "1", "", false)
+	if err := s.RemoveToken("1", ""); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, "1", "a", true)
+}
+
+func testEmptyUser(t *testing.T, s session.Storage, _ func(time.Duration)) {
+	save(t, s, "1", "a", 60)
+	check(t, s, "", "a", false)
+	// an empty user ID matches no user
+	if err := s.RemoveUser(""); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, "1", "a", true)
+}
+
+func testConcurrent(t *testing.T, s session.Storage, _ func(time.Duration)) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userID := fmt.Sprint(i % 3)
+			token := fmt.Sprint("t", i)
+			if err := s.SaveToken(userID, token, 60); err != nil {
+				errs <- err
+				return
+			}
+			ok, err := s.CheckAndRefreshToken(userID, token, 60)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !ok {
+				errs <- fmt.Errorf("token %s of user %s not found", token, userID)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}